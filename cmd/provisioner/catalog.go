@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"multicloud-iac-provisioner/pkg/config"
+)
+
+// imageLabel formats a catalog image for display.
+func imageLabel(image interface{}) string {
+	switch img := image.(type) {
+	case nil:
+		return "-"
+	case string:
+		return img
+	case map[string]interface{}:
+		if _, ok := img["publisher"]; ok {
+			return fmt.Sprintf("%v:%v:%v:%v", img["publisher"], img["offer"], img["sku"], img["version"])
+		}
+		if name, ok := img["name"]; ok {
+			return fmt.Sprintf("%v (owner %v)", name, img["owner"])
+		}
+	}
+	data, _ := json.Marshal(image)
+	return string(data)
+}
+
+// runCatalog shows what each abstract size and OS maps to and the accepted
+// machine types on the given providers (all when empty).
+func runCatalog(rootPath string, providers []string) error {
+	catalog, err := config.LoadCatalog(rootPath)
+	if err != nil {
+		return err
+	}
+
+	if len(providers) == 0 {
+		providers = supportedProviders
+	}
+	var headers []string
+	for _, provider := range providers {
+		check, ok := credentialChecks[provider]
+		if !ok {
+			return fmt.Errorf("unknown provider %q (expected one of: %s)", provider, strings.Join(supportedProviders, ", "))
+		}
+		headers = append(headers, strings.ToUpper(check.DisplayName))
+	}
+
+	fmt.Printf("Catalog %s\n", catalog.Version)
+
+	fmt.Println("\nSizes:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  SIZE\tVCPU\tMEMORY\t%s\n", strings.Join(headers, "\t"))
+	for _, name := range catalog.SizeNames() {
+		size := catalog.Sizes[name]
+		row := []string{name, fmt.Sprint(size.VCPU), fmt.Sprintf("%g GB", size.MemoryGB)}
+		for _, provider := range providers {
+			machineType := size.MachineTypes[provider]
+			if machineType == "" {
+				machineType = "-"
+			}
+			row = append(row, machineType)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nMachine types (use as machine_type instead of size):")
+	for i, provider := range providers {
+		fmt.Printf("  %s: %s\n", headers[i], strings.Join(catalog.MachineTypes[provider], ", "))
+	}
+
+	fmt.Println("\nOperating systems:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  OS\tNAME\t%s\n", strings.Join(headers, "\t"))
+	for _, name := range catalog.OSNames() {
+		spec := catalog.OS[name]
+		row := []string{name, spec.Name}
+		for _, provider := range providers {
+			row = append(row, imageLabel(spec.Images[provider]))
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, alias := range catalog.OSAliasNames() {
+		fmt.Printf("  %s is an alias for %s\n", alias, catalog.OSAliases[alias])
+	}
+
+	fmt.Println("\nDatabase sizes:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  SIZE\t%s\n", strings.Join(headers, "\t"))
+	for _, name := range catalog.SizeNames() {
+		row := []string{name}
+		for _, provider := range providers {
+			tier := catalog.Sizes[name].DatabaseTiers[provider]
+			if tier == "" {
+				tier = "-"
+			}
+			row = append(row, tier)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nCluster node sizes:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  SIZE\t%s\n", strings.Join(headers, "\t"))
+	for _, name := range catalog.SizeNames() {
+		row := []string{name}
+		for _, provider := range providers {
+			nodeType := catalog.Sizes[name].NodeTypes[provider]
+			if nodeType == "" {
+				nodeType = "-"
+			}
+			row = append(row, nodeType)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nDatabase engines:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  ENGINE\tVERSION\t%s\n", strings.Join(headers, "\t"))
+	for _, engine := range catalog.EngineNames() {
+		spec := catalog.Engines[engine]
+		for _, version := range catalog.EngineVersions(engine) {
+			label := version
+			if version == spec.DefaultVersion {
+				label += " (default)"
+			}
+			row := []string{engine, label}
+			for _, provider := range providers {
+				providerVersion := spec.Versions[version][provider]
+				if providerVersion == "" {
+					providerVersion = "-"
+				}
+				row = append(row, providerVersion)
+			}
+			fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nFunction runtimes:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  RUNTIME\tNAME\t%s\n", strings.Join(headers, "\t"))
+	for _, name := range catalog.RuntimeNames() {
+		spec := catalog.Runtimes[name]
+		row := []string{name, spec.Name}
+		for _, provider := range providers {
+			runtime := spec.Runtimes[provider]
+			if runtime == "" {
+				runtime = "-"
+			}
+			row = append(row, runtime)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
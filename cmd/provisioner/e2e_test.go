@@ -72,7 +72,7 @@ func TestE2E_ProvisionAndDestroy(t *testing.T) {
 
 			// 2. Run Provision
 			fmt.Printf(">>> Starting Provisioning for %s\n", exampleRelPath)
-			err = runProvision(configPath, rootPath, true)
+			err = runProvision(configPath, rootPath, provisionOptions{SkipConfirm: true})
 			if err != nil {
 				t.Logf("Provisioning failed for %s: %v", exampleRelPath, err)
 				t.Log("Skipping destroy verification due to provisioning failure (this is expected if credentials are missing)")
@@ -86,7 +86,7 @@ func TestE2E_ProvisionAndDestroy(t *testing.T) {
 			// Calculate expected output path to verify existence
 			// We need to call GeneratePlan again or replicate logic? 
 			// Let's call GeneratePlan, it's safe.
-			plan, err := config.GeneratePlan(configPath, rootPath)
+			plan, err := config.GeneratePlan(configPath, rootPath, config.PlanOptions{})
 			if err != nil {
 				t.Fatalf("Failed to generate plan for verification: %v", err)
 			}
@@ -105,7 +105,7 @@ func TestE2E_ProvisionAndDestroy(t *testing.T) {
 
 			// 3. Run Destroy
 			fmt.Printf(">>> Starting Destruction for %s\n", exampleRelPath)
-			err = runDestroy(plan.OutputDir)
+			err = runDestroy(plan.OutputDir, rootPath, "")
 			if err != nil {
 				t.Errorf("Destruction failed for %s: %v", exampleRelPath, err)
 			}
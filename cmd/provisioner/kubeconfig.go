@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfigFile is written into the directory of every container.cluster.
+const kubeconfigFile = "kubeconfig"
+
+// writeKubeconfig stores the kubeconfig output of a cluster next to its state,
+// readable by the owner only, and returns its path.
+func writeKubeconfig(resourceDir string, outputs map[string]TofuOutput) (string, error) {
+	content := outputString(outputs, "kubeconfig")
+	if content == "" {
+		return "", fmt.Errorf("no kubeconfig output found; is it a container.cluster?")
+	}
+
+	path := filepath.Join(resourceDir, kubeconfigFile)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// removeKubeconfig deletes the kubeconfig of a destroyed cluster, if any.
+func removeKubeconfig(resourceDir string) error {
+	err := os.Remove(filepath.Join(resourceDir, kubeconfigFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// defaultKubeconfigPath is the file kubectl reads: the first entry of
+// $KUBECONFIG, or ~/.kube/config.
+func defaultKubeconfigPath() (string, error) {
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if path != "" {
+			return path, nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// kubeconfigName returns the name of a clusters, contexts or users entry.
+func kubeconfigName(entry interface{}) string {
+	if m, ok := entry.(map[string]interface{}); ok {
+		name, _ := m["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// mergeKubeconfig adds the clusters, contexts and users of added to existing,
+// replacing entries of the same name, and switches to the current context of
+// added. It returns the merged file and that context.
+func mergeKubeconfig(existing, added []byte) ([]byte, string, error) {
+	var base, extra map[string]interface{}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := yaml.Unmarshal(existing, &base); err != nil {
+			return nil, "", fmt.Errorf("error parsing existing kubeconfig: %w", err)
+		}
+	}
+	if base == nil {
+		base = map[string]interface{}{"apiVersion": "v1", "kind": "Config"}
+	}
+	if err := yaml.Unmarshal(added, &extra); err != nil {
+		return nil, "", fmt.Errorf("error parsing cluster kubeconfig: %w", err)
+	}
+
+	for _, key := range []string{"clusters", "contexts", "users"} {
+		entries, _ := base[key].([]interface{})
+		additions, _ := extra[key].([]interface{})
+		for _, addition := range additions {
+			replaced := false
+			for i, entry := range entries {
+				if kubeconfigName(entry) == kubeconfigName(addition) {
+					entries[i] = addition
+					replaced = true
+				}
+			}
+			if !replaced {
+				entries = append(entries, addition)
+			}
+		}
+		base[key] = entries
+	}
+
+	context, _ := extra["current-context"].(string)
+	if context != "" {
+		base["current-context"] = context
+	}
+
+	var merged bytes.Buffer
+	encoder := yaml.NewEncoder(&merged)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(base); err != nil {
+		return nil, "", err
+	}
+	return merged.Bytes(), context, nil
+}
+
+// findClusters returns the container.cluster directories of a provisioning
+// directory.
+func findClusters(provisionDir string) ([]string, error) {
+	state, err := readState(provisionDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(provisionDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading provisioning directory: %w", err)
+	}
+
+	var clusters []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		res := state.resource(entry.Name())
+		if res != nil && res.Type == "container.cluster" {
+			clusters = append(clusters, entry.Name())
+			continue
+		}
+		// Directories missing from the state still have their kubeconfig
+		if _, err := os.Stat(filepath.Join(provisionDir, entry.Name(), kubeconfigFile)); res == nil && err == nil {
+			clusters = append(clusters, entry.Name())
+		}
+	}
+	return clusters, nil
+}
+
+// runKubeconfig prints the kubeconfig of a cluster, or merges it into the
+// kubeconfig at into (default: the one kubectl reads). cluster may be empty
+// when the directory holds one cluster only.
+func runKubeconfig(provisionDir, cluster string, merge bool, into string) error {
+	absProvisionDir, err := filepath.Abs(provisionDir)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path: %w", err)
+	}
+
+	clusters, err := findClusters(absProvisionDir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(clusters) == 0:
+		return fmt.Errorf("no container.cluster found in %s", absProvisionDir)
+	case cluster == "" && len(clusters) > 1:
+		return fmt.Errorf("%s holds several clusters; name one of: %s", absProvisionDir, strings.Join(clusters, ", "))
+	case cluster == "":
+		cluster = clusters[0]
+	case !containsDir(clusters, cluster):
+		return fmt.Errorf("cluster %q not found in %s (clusters: %s)", cluster, absProvisionDir, strings.Join(clusters, ", "))
+	}
+
+	// Clusters provisioned before the kubeconfig was written get it from
+	// their outputs
+	resourceDir := filepath.Join(absProvisionDir, cluster)
+	path := filepath.Join(resourceDir, kubeconfigFile)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		outputs, outputErr := readOutputs(resourceDir)
+		if outputErr != nil {
+			return fmt.Errorf("error reading outputs for %s: %w", cluster, outputErr)
+		}
+		if path, err = writeKubeconfig(resourceDir, outputs); err != nil {
+			return err
+		}
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading kubeconfig of %s: %w", cluster, err)
+	}
+	if err := checkKeyPermissions(path); err != nil {
+		return err
+	}
+
+	if !merge {
+		fmt.Print(string(content))
+		return nil
+	}
+
+	if into == "" {
+		if into, err = defaultKubeconfigPath(); err != nil {
+			return err
+		}
+	}
+	existing, err := os.ReadFile(into)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %w", into, err)
+	}
+	merged, context, err := mergeKubeconfig(existing, content)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(into), 0700); err != nil {
+		return fmt.Errorf("error creating %s: %w", filepath.Dir(into), err)
+	}
+	if err := os.WriteFile(into, merged, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", into, err)
+	}
+	// The merged file holds the cluster's credentials now
+	if err := os.Chmod(into, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Merged %s into %s (current context: %s)\n", cluster, into, context)
+	return nil
+}
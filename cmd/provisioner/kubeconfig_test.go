@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const clusterKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: k8s
+    cluster:
+      server: https://k8s.example.com
+contexts:
+  - name: k8s
+    context:
+      cluster: k8s
+      user: k8s
+users:
+  - name: k8s
+    user:
+      token: new
+current-context: k8s
+`
+
+func TestMergeKubeconfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		clusters []string
+		users    map[string]string
+	}{
+		{"no kubeconfig yet", "", []string{"k8s"}, map[string]string{"k8s": "new"}},
+		{"other clusters are kept", `apiVersion: v1
+kind: Config
+clusters:
+  - name: minikube
+    cluster:
+      server: https://127.0.0.1:8443
+users:
+  - name: minikube
+    user:
+      token: local
+current-context: minikube
+preferences: {}
+`, []string{"minikube", "k8s"}, map[string]string{"minikube": "local", "k8s": "new"}},
+		{"entries of the same name are replaced", `clusters:
+  - name: k8s
+    cluster:
+      server: https://old.example.com
+users:
+  - name: k8s
+    user:
+      token: old
+`, []string{"k8s"}, map[string]string{"k8s": "new"}},
+	}
+	for _, tt := range tests {
+		merged, context, err := mergeKubeconfig([]byte(tt.existing), []byte(clusterKubeconfig))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if context != "k8s" {
+			t.Errorf("%s: context %q, want k8s", tt.name, context)
+		}
+
+		var config struct {
+			Clusters []struct {
+				Name    string `yaml:"name"`
+				Cluster struct {
+					Server string `yaml:"server"`
+				} `yaml:"cluster"`
+			} `yaml:"clusters"`
+			Users []struct {
+				Name string `yaml:"name"`
+				User struct {
+					Token string `yaml:"token"`
+				} `yaml:"user"`
+			} `yaml:"users"`
+			CurrentContext string `yaml:"current-context"`
+		}
+		if err := yaml.Unmarshal(merged, &config); err != nil {
+			t.Fatalf("%s: merged kubeconfig is invalid: %v", tt.name, err)
+		}
+		var clusters []string
+		for _, cluster := range config.Clusters {
+			clusters = append(clusters, cluster.Name)
+			if cluster.Name == "k8s" && cluster.Cluster.Server != "https://k8s.example.com" {
+				t.Errorf("%s: k8s server %q was not replaced", tt.name, cluster.Cluster.Server)
+			}
+		}
+		if !reflect.DeepEqual(clusters, tt.clusters) {
+			t.Errorf("%s: clusters %v, want %v", tt.name, clusters, tt.clusters)
+		}
+		users := map[string]string{}
+		for _, user := range config.Users {
+			users[user.Name] = user.User.Token
+		}
+		if !reflect.DeepEqual(users, tt.users) {
+			t.Errorf("%s: users %v, want %v", tt.name, users, tt.users)
+		}
+		if config.CurrentContext != "k8s" {
+			t.Errorf("%s: current-context %q, want k8s", tt.name, config.CurrentContext)
+		}
+	}
+
+	if _, _, err := mergeKubeconfig([]byte("clusters: ["), []byte(clusterKubeconfig)); err == nil || !strings.Contains(err.Error(), "existing kubeconfig") {
+		t.Errorf("got %v, want an error parsing the existing kubeconfig", err)
+	}
+}
+
+func TestKubeconfigFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no file modes on Windows")
+	}
+	provisionDir := t.TempDir()
+	resourceDir := filepath.Join(provisionDir, "k8s")
+	if err := os.Mkdir(resourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeState(provisionDir, &provisionState{Resources: []resourceState{{ID: "k8s", Type: "container.cluster", Provider: "gcp"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A kubeconfig left readable by others is tightened when rewritten
+	stale := filepath.Join(resourceDir, kubeconfigFile)
+	if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := writeKubeconfig(resourceDir, map[string]TofuOutput{"kubeconfig": {Value: clusterKubeconfig, Sensitive: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMode := func(path string) {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("%s: mode %#o, want 0600", path, mode)
+		}
+	}
+	checkMode(path)
+
+	into := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(into, []byte("apiVersion: v1\nkind: Config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runKubeconfig(provisionDir, "", true, into); err != nil {
+		t.Fatal(err)
+	}
+	checkMode(into)
+	merged, err := os.ReadFile(into)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(merged), "https://k8s.example.com") {
+		t.Errorf("merged kubeconfig lacks the cluster:\n%s", merged)
+	}
+
+	if err := runKubeconfig(provisionDir, "other", true, into); err == nil || !strings.Contains(err.Error(), `cluster "other" not found`) {
+		t.Errorf("got %v, want an unknown cluster error", err)
+	}
+}
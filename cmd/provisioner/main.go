@@ -3,22 +3,25 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/joho/godotenv"
 
+	"multicloud-iac-provisioner/pkg/bundle"
 	"multicloud-iac-provisioner/pkg/config"
+	"multicloud-iac-provisioner/pkg/sshkey"
 )
 
-func runCommand(dir string, name string, args ...string) error {
+func runCommand(dir string, env []string, name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
+	cmd.Env = env
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	fmt.Printf("➜ Running %s %v in %s\n", name, args, dir)
@@ -26,62 +29,48 @@ func runCommand(dir string, name string, args ...string) error {
 }
 
 type TofuOutput struct {
-	Value interface{} `json:"value"`
+	Value     interface{} `json:"value"`
+	Sensitive bool        `json:"sensitive"`
 }
 
-func getModuleOutputs(modulePath string) ([]string, error) {
-	files, err := os.ReadDir(modulePath)
+func readOutputs(dir string) (map[string]TofuOutput, error) {
+	cmd := exec.Command("tofu", "output", "-json")
+	cmd.Dir = dir
+	outputBytes, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
-	var outputs []string
-	// Regex to find: output "name" {
-	re := regexp.MustCompile(`output\s+\"([\w_-]+)\"\s+\{`)
-
-	for _, file := range files {
-		if filepath.Ext(file.Name()) != ".tf" {
-			continue
-		}
-
-		content, err := os.ReadFile(filepath.Join(modulePath, file.Name()))
-		if err != nil {
-			return nil, err
-		}
-
-		matches := re.FindAllStringSubmatch(string(content), -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				outputs = append(outputs, match[1])
-			}
-		}
+	var outputs map[string]TofuOutput
+	if err := json.Unmarshal(outputBytes, &outputs); err != nil {
+		return nil, fmt.Errorf("error parsing output json: %w", err)
 	}
 	return outputs, nil
 }
 
 func printOutputs(dir string) error {
-	cmd := exec.Command("tofu", "output", "-json")
-	cmd.Dir = dir
-	outputBytes, err := cmd.Output()
+	outputs, err := readOutputs(dir)
 	if err != nil {
 		return err
 	}
+	printOutputValues(outputs)
+	return nil
+}
 
-	var outputs map[string]TofuOutput
-	if err := json.Unmarshal(outputBytes, &outputs); err != nil {
-		return fmt.Errorf("error parsing output json: %w", err)
-	}
-
+func printOutputValues(outputs map[string]TofuOutput) {
 	if len(outputs) == 0 {
 		fmt.Println("  (No outputs found)")
-		return nil
+		return
 	}
 
 	fmt.Println("  Outputs:")
 	for key, val := range outputs {
+		if val.Sensitive {
+			fmt.Printf("    %s: (sensitive)\n", key)
+			continue
+		}
 		fmt.Printf("    %s: %v\n", key, val.Value)
 	}
-	return nil
 }
 
 func runOutput(provisionDir string) error {
@@ -95,46 +84,146 @@ func runOutput(provisionDir string) error {
 		return fmt.Errorf("error reading provisioning directory: %w", err)
 	}
 
+	state, err := readState(absProvisionDir)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Retrieving outputs from: %s\n", absProvisionDir)
 
+	// Group resources by region when they span several
+	var regions []string
+	byRegion := map[string][]string{}
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
+		region := ""
+		if res := state.resource(entry.Name()); res != nil {
+			region = res.Region
+		}
+		if _, seen := byRegion[region]; !seen {
+			regions = append(regions, region)
+		}
+		byRegion[region] = append(byRegion[region], entry.Name())
+	}
 
-		fmt.Printf("\n----------------------------------------------------------------\n")
-		fmt.Printf("Resource: %s\n", entry.Name())
-		fmt.Printf("----------------------------------------------------------------\n")
+	for _, region := range regions {
+		if len(regions) > 1 {
+			label := region
+			if label == "" {
+				label = "(unknown)"
+			}
+			fmt.Printf("\n================================================================\n")
+			fmt.Printf("Region: %s\n", label)
+			fmt.Printf("================================================================\n")
+		}
+
+		for _, name := range byRegion[region] {
+			fmt.Printf("\n----------------------------------------------------------------\n")
+			fmt.Printf("Resource: %s\n", name)
+			fmt.Printf("----------------------------------------------------------------\n")
 
-		resourceDir := filepath.Join(absProvisionDir, entry.Name())
-		if err := printOutputs(resourceDir); err != nil {
-			fmt.Printf("❌ Error retrieving outputs: %v\n", err)
+			resourceDir := filepath.Join(absProvisionDir, name)
+			if err := printOutputs(resourceDir); err != nil {
+				fmt.Printf("❌ Error retrieving outputs: %v\n", err)
+			}
 		}
 	}
 	fmt.Println("\n================================================================")
 	return nil
 }
 
-func runProvision(configPath string, rootPath string, skipConfirm bool) error {
+// resourceModuleDir returns the OpenTofu module a resource is provisioned from.
+func resourceModuleDir(rootPath string, res config.ResourcePlan) string {
+	return filepath.Join(rootPath, "opentofu", res.Provider, res.ModuleDir)
+}
+
+type provisionOptions struct {
+	SkipConfirm bool
+	// Profile overrides the config's credentials_profile.
+	Profile string
+	// Env selects the environment overlay applied to the config.
+	Env string
+	// VerifyCreds checks the credentials of every provider in the plan first.
+	VerifyCreds bool
+}
+
+func runProvision(configPath string, rootPath string, opts provisionOptions) error {
 	// Generate Plan
-	plan, err := config.GeneratePlan(configPath, rootPath)
+	plan, err := config.GeneratePlan(configPath, rootPath, config.PlanOptions{Env: opts.Env})
 	if err != nil {
 		return fmt.Errorf("error generating plan: %w", err)
 	}
 
+	profile := plan.CredentialsProfile
+	if opts.Profile != "" {
+		profile = opts.Profile
+	}
+	env, err := profileEnv(rootPath, profile)
+	if err != nil {
+		return err
+	}
+
+	if err := preflightChecks(plan, rootPath, envLookup(env)); err != nil {
+		return err
+	}
+
+	if opts.VerifyCreds {
+		if err := runVerifyCreds(plan.Providers(), "text", env); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("✓ Plan generated. Output directory: %s\n", plan.OutputDir)
 	
 	fmt.Println("\nProvisioning Plan:")
-	fmt.Printf("  Cloud Provider: %s\n", plan.Provider)
+	if providers := plan.Providers(); len(providers) > 1 {
+		fmt.Printf("  Cloud Providers: %s\n", strings.Join(providers, ", "))
+	} else {
+		fmt.Printf("  Cloud Provider: %s\n", plan.Provider)
+	}
 	fmt.Printf("  Region: %s\n", plan.Region)
+	if plan.Environment != "" {
+		fmt.Printf("  Environment: %s\n", plan.Environment)
+	}
+	fmt.Printf("  Credentials Profile: %s\n", profileLabel(profile))
 	fmt.Printf("  Output Directory: %s\n", plan.OutputDir)
 	fmt.Printf("  Resources (%d):\n", len(plan.Resources))
 	for _, res := range plan.Resources {
-		fmt.Printf("    - %s (Type: %s)\n", res.ID, res.Type)
+		details := "Type: " + res.Type
+		if res.Provider != plan.Provider {
+			details += ", Provider: " + res.Provider
+		}
+		if res.Region != plan.Region {
+			details += ", Region: " + res.Region
+		}
+		if res.Zone != "" {
+			details += ", Zone: " + res.Zone
+		}
+		if len(res.DependsOn) > 0 {
+			details += ", After: " + strings.Join(res.DependsOn, ", ")
+		}
+		fmt.Printf("    - %s (%s)\n", res.ID, details)
 	}
 	fmt.Println()
 
-	if !skipConfirm {
+	// Resources removed from the config (e.g. by lowering a count) are not
+	// destroyed automatically
+	previous, err := readState(plan.OutputDir)
+	if err != nil {
+		return err
+	}
+	orphans := orphanedResources(previous, plan)
+	for _, res := range orphans {
+		fmt.Printf("⚠️  Warning: %s is no longer in the config but is still provisioned in %s\n", res.ID, filepath.Join(plan.OutputDir, res.ID))
+	}
+	if len(orphans) > 0 {
+		fmt.Println("   Run 'tofu destroy' in the resource directory to remove it, or destroy the whole project.")
+		fmt.Println()
+	}
+
+	if !opts.SkipConfirm {
 		fmt.Print("Do you want to proceed? [y/N]: ")
 		reader := bufio.NewReader(os.Stdin)
 		response, _ := reader.ReadString('\n')
@@ -151,10 +240,27 @@ func runProvision(configPath string, rootPath string, skipConfirm bool) error {
 		return fmt.Errorf("error creating output directory: %w", err)
 	}
 
+	state := &provisionState{CredentialsProfile: profile, Environment: plan.Environment}
+	for _, res := range plan.Resources {
+		state.Resources = append(state.Resources, resourceState{ID: res.ID, Type: res.Type, Provider: res.Provider, Region: res.Region})
+	}
+	state.Resources = append(state.Resources, orphans...)
+	if err := writeState(plan.OutputDir, state); err != nil {
+		return err
+	}
+
+	// Outputs of the resources provisioned so far, for user data templates,
+	// and the values of the sensitive ones among them
+	outputs := map[string]map[string]interface{}{}
+	var sensitiveValues []string
+
+	// The passphrase may come from .env, like the credentials
+	passphrase, _ := envLookup(env)("SSH_KEY_PASSPHRASE")
+
 	// Execute Plan
 	for _, res := range plan.Resources {
 		fmt.Printf("\n----------------------------------------------------------------\n")
-		fmt.Printf("Provisioning Resource: %s (Type: %s)\n", res.ID, res.Type)
+		fmt.Printf("Provisioning Resource: %s (Type: %s, Provider: %s)\n", res.ID, res.Type, res.Provider)
 		fmt.Printf("----------------------------------------------------------------\n")
 
 		targetDir := filepath.Join(plan.OutputDir, res.ID)
@@ -162,8 +268,55 @@ func runProvision(configPath string, rootPath string, skipConfirm bool) error {
 			return fmt.Errorf("error creating resource directory %s: %w", targetDir, err)
 		}
 
+		tfVars := res.TfVars
+		sensitive := res.Sensitive
+		if res.GenerateSSHKey {
+			key, created, err := sshkey.Ensure(targetDir, res.ID, []byte(passphrase))
+			if err != nil {
+				return fmt.Errorf("error preparing SSH key for %s: %w", res.ID, err)
+			}
+			if created {
+				fmt.Printf("✓ Generated SSH key %s\n", key.PrivateKeyPath)
+			} else {
+				fmt.Printf("✓ Reusing SSH key %s\n", key.PrivateKeyPath)
+			}
+			tfVars = config.AppendTfvars(tfVars, map[string]interface{}{
+				"ssh_public_key":   key.PublicKey,
+				"private_key_path": key.PrivateKeyPath,
+			})
+		}
+
+		if res.FunctionSource != "" {
+			archive, err := bundle.Build(res.FunctionSource, targetDir, "function")
+			if err != nil {
+				return fmt.Errorf("error packaging %s: %w", res.ID, err)
+			}
+			fmt.Printf("✓ Packaged %s into %s (files: %d, %d bytes)\n", res.FunctionSource, archive.Path, archive.Files, archive.Size)
+			tfVars = config.AppendTfvars(tfVars, map[string]interface{}{"source_zip": archive.Path})
+		}
+
+		if len(res.Inputs) > 0 {
+			inputs, err := res.ResolveInputs(outputs)
+			if err != nil {
+				return fmt.Errorf("error resolving inputs for %s: %w", res.ID, err)
+			}
+			tfVars = config.AppendTfvars(tfVars, inputs)
+		}
+
+		if res.UserData != nil {
+			userData, err := res.UserData.Render(outputs)
+			if err != nil {
+				return fmt.Errorf("error rendering user data for %s: %w", res.ID, err)
+			}
+			tfVars = config.AppendTfvars(tfVars, map[string]interface{}{"user_data": userData})
+			for _, value := range sensitiveValues {
+				sensitive = sensitive || strings.Contains(userData, value)
+			}
+			fmt.Printf("✓ Rendered user data from %s (%d bytes)\n", res.UserData.Name, len(userData))
+		}
+
 		// 1. Resolve Module Path
-		moduleSource := filepath.Join(rootPath, "opentofu", plan.Provider, res.ModuleDir)
+		moduleSource := resourceModuleDir(rootPath, res)
 
 		// Check if module exists
 		if _, err := os.Stat(moduleSource); os.IsNotExist(err) {
@@ -177,7 +330,7 @@ func runProvision(configPath string, rootPath string, skipConfirm bool) error {
 		}
 
 		// Detect outputs from the module
-		moduleOutputs, err := getModuleOutputs(absModuleSource)
+		moduleOutputs, err := config.ModuleOutputs(absModuleSource)
 		if err != nil {
 			fmt.Printf("⚠️  Warning: Could not scan module outputs: %v\n", err)
 		}
@@ -185,11 +338,15 @@ func runProvision(configPath string, rootPath string, skipConfirm bool) error {
 		// Build output forwarding blocks
 		var outputBlocks string
 		for _, out := range moduleOutputs {
+			marker := ""
+			if out.Sensitive {
+				marker = "\n  sensitive = true"
+			}
 			outputBlocks += fmt.Sprintf(`
 output "%s" {
-  value = module.provision.%s
+  value = module.provision.%s%s
 }
-`, out, out)
+`, out.Name, out.Name, marker)
 		}
 
 		// 2. Generate main.tf with Module Reference AND Output Forwarding
@@ -201,30 +358,55 @@ module "provision" {
 }
 
 %s
-`, absModuleSource, res.TfVars, outputBlocks)
+`, absModuleSource, tfVars, outputBlocks)
+
+		// Keep secret values readable by the owner only
+		mainTfMode := os.FileMode(0644)
+		if sensitive {
+			mainTfMode = 0600
+		}
 
 		mainTfPath := filepath.Join(targetDir, "main.tf")
-		if err := os.WriteFile(mainTfPath, []byte(mainTfContent), 0644); err != nil {
+		if err := os.WriteFile(mainTfPath, []byte(mainTfContent), mainTfMode); err != nil {
 			return fmt.Errorf("error writing main.tf for %s: %w", res.ID, err)
 		}
 		fmt.Printf("✓ Generated main.tf referencing module at %s\n", absModuleSource)
 
 		// 3. Tofu Init
 		// We use -upgrade to ensure that if the source path content changed or we are switching dev modes, it updates.
-		if err := runCommand(targetDir, "tofu", "init", "-upgrade"); err != nil {
+		if err := runCommand(targetDir, env, "tofu", "init", "-upgrade"); err != nil {
 			return fmt.Errorf("error initializing OpenTofu for %s: %w", res.ID, err)
 		}
 
 		// 4. Tofu Apply
-		if err := runCommand(targetDir, "tofu", "apply", "-auto-approve"); err != nil {
+		if err := runCommand(targetDir, env, "tofu", "apply", "-auto-approve"); err != nil {
 			return fmt.Errorf("error applying OpenTofu for %s: %w", res.ID, err)
 		}
 
 		fmt.Printf("✓ Successfully provisioned %s\n", res.ID)
 
 		// 5. Display Outputs
-		if err := printOutputs(targetDir); err != nil {
+		resOutputs, err := readOutputs(targetDir)
+		if err != nil {
 			fmt.Printf("⚠️  Warning: Could not retrieve outputs for %s: %v\n", res.ID, err)
+			continue
+		}
+		printOutputValues(resOutputs)
+
+		if res.Type == "container.cluster" {
+			if path, err := writeKubeconfig(targetDir, resOutputs); err != nil {
+				fmt.Printf("⚠️  Warning: Could not write kubeconfig for %s: %v\n", res.ID, err)
+			} else {
+				fmt.Printf("✓ Wrote kubeconfig %s\n", path)
+			}
+		}
+
+		outputs[res.ID] = map[string]interface{}{}
+		for key, val := range resOutputs {
+			outputs[res.ID][key] = val.Value
+			if s, ok := val.Value.(string); ok && val.Sensitive && s != "" {
+				sensitiveValues = append(sensitiveValues, s)
+			}
 		}
 	}
 
@@ -234,13 +416,28 @@ module "provision" {
 	return nil
 }
 
-func runDestroy(provisionDir string) error {
+// runDestroy tears down a provisioning directory. An empty profile falls back
+// to the profile recorded when the resources were provisioned.
+func runDestroy(provisionDir string, rootPath string, profile string) error {
 	absProvisionDir, err := filepath.Abs(provisionDir)
 	if err != nil {
 		return fmt.Errorf("error getting absolute path: %w", err)
 	}
 
+	state, err := readState(absProvisionDir)
+	if err != nil {
+		return err
+	}
+	if profile == "" {
+		profile = state.CredentialsProfile
+	}
+	env, err := profileEnv(rootPath, profile)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Destroying provisioning at: %s\n", absProvisionDir)
+	fmt.Printf("Credentials Profile: %s\n", profileLabel(profile))
 
 	entries, err := os.ReadDir(absProvisionDir)
 	if err != nil {
@@ -249,29 +446,43 @@ func runDestroy(provisionDir string) error {
 
 	allDestroyed := true
 
-	// Iterate over subdirectories (resources)
+	var dirs []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
 		}
+	}
 
-		resourceDir := filepath.Join(absProvisionDir, entry.Name())
+	// Iterate over subdirectories (resources), dependents first
+	for _, name := range destroyOrder(state, dirs) {
+		resourceDir := filepath.Join(absProvisionDir, name)
 		fmt.Printf("\n----------------------------------------------------------------\n")
-		fmt.Printf("Destroying Resource: %s\n", entry.Name())
+		if res := state.resource(name); res != nil {
+			fmt.Printf("Destroying Resource: %s (Provider: %s)\n", name, res.Provider)
+		} else {
+			fmt.Printf("Destroying Resource: %s\n", name)
+		}
 		fmt.Printf("----------------------------------------------------------------\n")
 
 		// Check if it's a valid tofu directory (has .terraform or .tf files)
 		// We'll just assume if it has terraform.tfstate or main.tf it's valid
 		// The safest check is trying to run tofu destroy.
 
-		if err := runCommand(resourceDir, "tofu", "destroy", "-auto-approve"); err != nil {
-			fmt.Printf("❌ Error destroying %s: %v\n", entry.Name(), err)
+		if err := runCommand(resourceDir, env, "tofu", "destroy", "-auto-approve"); err != nil {
+			fmt.Printf("❌ Error destroying %s: %v\n", name, err)
 			allDestroyed = false
 			// Continue destroying other resources even if one fails
 			continue
 		}
 
-		fmt.Printf("✓ Successfully destroyed %s\n", entry.Name())
+		fmt.Printf("✓ Successfully destroyed %s\n", name)
+
+		if err := sshkey.Remove(resourceDir, name); err != nil {
+			fmt.Printf("⚠️  Warning: Could not remove SSH key for %s: %v\n", name, err)
+		}
+		if err := removeKubeconfig(resourceDir); err != nil {
+			fmt.Printf("⚠️  Warning: Could not remove kubeconfig for %s: %v\n", name, err)
+		}
 	}
 
 	fmt.Printf("\n================================================================\n")
@@ -292,15 +503,25 @@ func runDestroy(provisionDir string) error {
 	return nil
 }
 
+func printUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  provisioner provision [-s] [--verify-creds] [--profile <name>] [--env <name>] <config.json>")
+	fmt.Println("  provisioner render [--env <name>] <config.json>")
+	fmt.Println("  provisioner output <provisioning_directory>")
+	fmt.Println("  provisioner destroy [--profile <name>] <provisioning_directory>")
+	fmt.Println("  provisioner ssh [--print] <provisioning_directory> <instance> [ssh options...] [-- command...]")
+	fmt.Println("  provisioner kubeconfig [--merge] [--into <path>] <provisioning_directory> [cluster]")
+	fmt.Println("  provisioner regions [aws|azure|gcp...]")
+	fmt.Println("  provisioner catalog [aws|azure|gcp...]")
+	fmt.Println("  provisioner verify-creds [--format text|json] [--config <config.json> [--env <name>]] [--profile <name>] [aws|azure|gcp...]")
+}
+
 func main() {
-	_ = godotenv.Load()
+	// .env only reaches the tofu processes, see profileEnv
+	dotEnv, _ = godotenv.Read()
 
 	if len(os.Args) < 2 {
-		fmt.Println("Usage:")
-		fmt.Println("  provisioner provision <config.json>")
-		fmt.Println("  provisioner output <provisioning_directory>")
-		fmt.Println("  provisioner destroy <provisioning_directory>")
-		fmt.Println("  provisioner verify-creds")
+		printUsage()
 		os.Exit(1)
 	}
 
@@ -331,22 +552,26 @@ func main() {
 		// Parse flags for the provision command
 		provisionCmd := flag.NewFlagSet("provision", flag.ExitOnError)
 		skipConfirm := provisionCmd.Bool("s", false, "Skip confirmation")
+		verifyCreds := provisionCmd.Bool("verify-creds", false, "Verify credentials of the config's providers before provisioning")
+		profile := provisionCmd.String("profile", "", "Credential profile from profiles.json (overrides credentials_profile)")
+		env := provisionCmd.String("env", "", "Environment overlay to apply (e.g. prod loads <config>.prod.<ext>)")
 
 		// Parse arguments excluding the program name and "provision" command
 		if err := provisionCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Println("Usage: provisioner provision [-s] <config.json>")
+			fmt.Println("Usage: provisioner provision [-s] [--verify-creds] [--profile <name>] [--env <name>] <config.json>")
 			os.Exit(1)
 		}
 
 		args := provisionCmd.Args()
 		if len(args) < 1 {
-			fmt.Println("Usage: provisioner provision [-s] <config.json>")
+			fmt.Println("Usage: provisioner provision [-s] [--verify-creds] [--profile <name>] [--env <name>] <config.json>")
 			os.Exit(1)
 		}
 
 		configPath := args[0]
 
-		if err := runProvision(configPath, rootPath, *skipConfirm); err != nil {
+		opts := provisionOptions{SkipConfirm: *skipConfirm, VerifyCreds: *verifyCreds, Profile: *profile, Env: *env}
+		if err := runProvision(configPath, rootPath, opts); err != nil {
 			fmt.Fprintf(os.Stderr, "❌ Provisioning failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -360,31 +585,125 @@ func main() {
 			os.Exit(1)
 		}
 	case "destroy":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: provisioner destroy <provisioning_directory>")
+		destroyCmd := flag.NewFlagSet("destroy", flag.ExitOnError)
+		profile := destroyCmd.String("profile", "", "Credential profile from profiles.json (defaults to the one used for provisioning)")
+
+		if err := destroyCmd.Parse(os.Args[2:]); err != nil || destroyCmd.NArg() < 1 {
+			fmt.Println("Usage: provisioner destroy [--profile <name>] <provisioning_directory>")
 			os.Exit(1)
 		}
-		if err := runDestroy(os.Args[2]); err != nil {
+		if err := runDestroy(destroyCmd.Arg(0), rootPath, *profile); err != nil {
 			fmt.Fprintf(os.Stderr, "❌ Destruction failed: %v\n", err)
 			os.Exit(1)
 		}
+	case "ssh":
+		sshCmd := flag.NewFlagSet("ssh", flag.ExitOnError)
+		printOnly := sshCmd.Bool("print", false, "Print the ssh command instead of running it")
+
+		if err := sshCmd.Parse(os.Args[2:]); err != nil {
+			fmt.Println("Usage: provisioner ssh [--print] <provisioning_directory> <instance> [ssh options...] [-- command...]")
+			os.Exit(1)
+		}
+
+		args := sshCmd.Args()
+		if len(args) < 2 {
+			fmt.Println("Usage: provisioner ssh [--print] <provisioning_directory> <instance> [ssh options...] [-- command...]")
+			os.Exit(1)
+		}
+
+		if err := runSSH(args[0], args[1], args[2:], *printOnly); err != nil {
+			// Propagate the remote exit status unchanged
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			fmt.Fprintf(os.Stderr, "❌ SSH failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "kubeconfig":
+		kubeconfigCmd := flag.NewFlagSet("kubeconfig", flag.ExitOnError)
+		merge := kubeconfigCmd.Bool("merge", false, "Merge into your kubeconfig instead of printing it")
+		into := kubeconfigCmd.String("into", "", "Kubeconfig to merge into (default: $KUBECONFIG or ~/.kube/config)")
+
+		if err := kubeconfigCmd.Parse(os.Args[2:]); err != nil || kubeconfigCmd.NArg() < 1 || kubeconfigCmd.NArg() > 2 {
+			fmt.Println("Usage: provisioner kubeconfig [--merge] [--into <path>] <provisioning_directory> [cluster]")
+			os.Exit(1)
+		}
+		if err := runKubeconfig(kubeconfigCmd.Arg(0), kubeconfigCmd.Arg(1), *merge, *into); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Kubeconfig failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "render":
+		renderCmd := flag.NewFlagSet("render", flag.ExitOnError)
+		env := renderCmd.String("env", "", "Environment overlay to apply")
+
+		if err := renderCmd.Parse(os.Args[2:]); err != nil || renderCmd.NArg() < 1 {
+			fmt.Println("Usage: provisioner render [--env <name>] <config.json>")
+			os.Exit(1)
+		}
+
+		rendered, err := config.RenderConfig(renderCmd.Arg(0), config.PlanOptions{Env: *env})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Render failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(rendered))
+	case "catalog":
+		if err := runCatalog(rootPath, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+	case "regions":
+		if err := runRegions(rootPath, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
 	case "verify-creds":
-		runVerifyCreds()
+		verifyCmd := flag.NewFlagSet("verify-creds", flag.ExitOnError)
+		format := verifyCmd.String("format", "text", "Output format: text or json")
+		configPath := verifyCmd.String("config", "", "Only verify the providers used by this config file")
+		profile := verifyCmd.String("profile", "", "Credential profile from profiles.json")
+		overlay := verifyCmd.String("env", "", "Environment overlay applied to --config")
+
+		if err := verifyCmd.Parse(os.Args[2:]); err != nil {
+			fmt.Println("Usage: provisioner verify-creds [--format text|json] [--config <config.json> [--env <name>]] [--profile <name>] [aws|azure|gcp...]")
+			os.Exit(1)
+		}
+
+		providers := verifyCmd.Args()
+		if *configPath != "" {
+			plan, err := config.GeneratePlan(*configPath, rootPath, config.PlanOptions{Env: *overlay})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error generating plan: %v\n", err)
+				os.Exit(1)
+			}
+			providers = append(providers, plan.Providers()...)
+			if *profile == "" {
+				*profile = plan.CredentialsProfile
+			}
+		}
+
+		env, err := profileEnv(rootPath, *profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runVerifyCreds(providers, *format, env); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		// Fallback for backward compatibility or direct config execution
-		// If first arg is a file that ends in .json, assume provision
-		if filepath.Ext(command) == ".json" {
-			if err := runProvision(command, rootPath, false); err != nil {
+		// If first arg is a config file (.json, .yaml, .yml, .hcl), assume provision
+		if config.IsConfigFile(command) {
+			if err := runProvision(command, rootPath, provisionOptions{}); err != nil {
 				fmt.Fprintf(os.Stderr, "❌ Provisioning failed: %v\n", err)
 				os.Exit(1)
 			}
 		} else {
 			fmt.Printf("Unknown command: %s\n", command)
-			fmt.Println("Usage:")
-			fmt.Println("  provisioner provision [-s] <config.json>")
-			fmt.Println("  provisioner output <provisioning_directory>")
-			fmt.Println("  provisioner destroy <provisioning_directory>")
-			fmt.Println("  provisioner verify-creds")
+			printUsage()
 			os.Exit(1)
 		}
 	}
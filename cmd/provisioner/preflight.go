@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"multicloud-iac-provisioner/pkg/config"
+)
+
+// requiredEnv lists the credential variables from .env.example each provider
+// cannot work without. Optional ones (session token, region) are not checked.
+var requiredEnv = map[string][]string{
+	"aws":   {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+	"azure": {"ARM_CLIENT_ID", "ARM_CLIENT_SECRET", "ARM_SUBSCRIPTION_ID", "ARM_TENANT_ID"},
+	"gcp":   {"GOOGLE_APPLICATION_CREDENTIALS"},
+}
+
+// checkCredentialsFile verifies GOOGLE_APPLICATION_CREDENTIALS points to a
+// readable service account (or user) JSON key.
+func checkCredentialsFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS is not readable: %v", err)
+	}
+
+	var key struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS (%s) is not a JSON key file: %v", path, err)
+	}
+	if key.Type == "" {
+		return fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS (%s) has no 'type' field; is it a credentials key?", path)
+	}
+	return nil
+}
+
+func checkProviderEnv(provider string, lookupEnv func(string) (string, bool)) []string {
+	var problems []string
+
+	// A named AWS CLI profile replaces static keys
+	if provider == "aws" {
+		if profile, ok := lookupEnv("AWS_PROFILE"); ok && profile != "" {
+			return nil
+		}
+	}
+
+	for _, name := range requiredEnv[provider] {
+		value, ok := lookupEnv(name)
+		if !ok || value == "" {
+			problems = append(problems, fmt.Sprintf("%s: environment variable %s is not set (see .env.example)", provider, name))
+			continue
+		}
+		if name == "GOOGLE_APPLICATION_CREDENTIALS" {
+			if err := checkCredentialsFile(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", provider, err))
+			}
+		}
+	}
+	return problems
+}
+
+// preflightChecks validates everything provisioning depends on before any
+// tofu command runs and reports all problems at once.
+func preflightChecks(plan *config.ProvisioningPlan, rootPath string, lookupEnv func(string) (string, bool)) error {
+	var problems []string
+
+	if _, err := exec.LookPath("tofu"); err != nil {
+		problems = append(problems, "OpenTofu executable 'tofu' not found on PATH")
+	}
+
+	for _, provider := range plan.Providers() {
+		problems = append(problems, checkProviderEnv(provider, lookupEnv)...)
+	}
+
+	for _, res := range plan.Resources {
+		moduleSource := resourceModuleDir(rootPath, res)
+		if info, err := os.Stat(moduleSource); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("%s: module not found at %s", res.ID, moduleSource))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("pre-flight checks failed:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"multicloud-iac-provisioner/pkg/config"
+)
+
+func TestPreflightChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tofu is a shell script")
+	}
+	root := t.TempDir()
+	for _, module := range []string{"aws/compute_instance", "gcp/storage_object"} {
+		if err := os.MkdirAll(filepath.Join(root, "opentofu", module), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "tofu"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(root, "key.json")
+	if err := os.WriteFile(key, []byte(`{"type": "service_account"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	notKey := filepath.Join(root, "notes.txt")
+	if err := os.WriteFile(notKey, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &config.ProvisioningPlan{Provider: "aws", Resources: []config.ResourcePlan{
+		{ID: "web", Provider: "aws", ModuleDir: "compute_instance"},
+		{ID: "assets", Provider: "gcp", ModuleDir: "storage_object"},
+	}}
+	complete := map[string]string{
+		"AWS_ACCESS_KEY_ID":              "key",
+		"AWS_SECRET_ACCESS_KEY":          "secret",
+		"GOOGLE_APPLICATION_CREDENTIALS": key,
+	}
+	with := func(changes map[string]string) map[string]string {
+		env := map[string]string{}
+		for k, v := range complete {
+			env[k] = v
+		}
+		for k, v := range changes {
+			if v == "" {
+				delete(env, k)
+			} else {
+				env[k] = v
+			}
+		}
+		return env
+	}
+
+	tests := []struct {
+		name string
+		path string
+		plan *config.ProvisioningPlan
+		env  map[string]string
+		want []string
+	}{
+		{"ready", bin, plan, complete, nil},
+		{"AWS profile instead of keys", bin, plan, with(map[string]string{"AWS_ACCESS_KEY_ID": "", "AWS_SECRET_ACCESS_KEY": "", "AWS_PROFILE": "dev"}), nil},
+		{"everything missing", t.TempDir(), &config.ProvisioningPlan{Resources: []config.ResourcePlan{
+			{ID: "vm", Provider: "azure", ModuleDir: "compute_instance"},
+		}}, map[string]string{"ARM_CLIENT_ID": "id"}, []string{
+			"OpenTofu executable 'tofu' not found on PATH",
+			"azure: environment variable ARM_CLIENT_SECRET is not set",
+			"azure: environment variable ARM_SUBSCRIPTION_ID is not set",
+			"azure: environment variable ARM_TENANT_ID is not set",
+			"vm: module not found at " + filepath.Join(root, "opentofu", "azure", "compute_instance"),
+		}},
+		{"missing AWS secret", bin, plan, with(map[string]string{"AWS_SECRET_ACCESS_KEY": ""}), []string{
+			"aws: environment variable AWS_SECRET_ACCESS_KEY is not set",
+		}},
+		{"unreadable GCP key", bin, plan, with(map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": filepath.Join(root, "missing.json")}), []string{
+			"gcp: GOOGLE_APPLICATION_CREDENTIALS is not readable",
+		}},
+		{"GCP key is not JSON", bin, plan, with(map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": notKey}), []string{
+			"is not a JSON key file",
+		}},
+	}
+	for _, tt := range tests {
+		t.Setenv("PATH", tt.path)
+		lookup := func(name string) (string, bool) {
+			value, ok := tt.env[name]
+			return value, ok
+		}
+
+		err := preflightChecks(tt.plan, root, lookup)
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected pre-flight problems", tt.name)
+			continue
+		}
+		if n := strings.Count(err.Error(), "\n  - "); n != len(tt.want) {
+			t.Errorf("%s: %d problems, want %d: %v", tt.name, n, len(tt.want), err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: %q does not report %q", tt.name, err, want)
+			}
+		}
+	}
+}
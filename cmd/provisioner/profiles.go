@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// profilesFile holds named credential profiles, keyed by profile name, each
+// mapping environment variable names to values (see profiles.example.json).
+const profilesFile = "profiles.json"
+
+func loadProfile(rootPath, name string) (map[string]string, error) {
+	path := filepath.Join(rootPath, profilesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading credential profiles from %s: %w", path, err)
+	}
+
+	var profiles map[string]map[string]string
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("error parsing credential profiles: %w", err)
+	}
+
+	vars, ok := profiles[name]
+	if !ok {
+		var names []string
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("credential profile %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+	}
+	return vars, nil
+}
+
+// credentialEnvPrefixes are the provider settings a profile replaces. The
+// parent environment and .env may hold others, which would otherwise mix
+// with the profile's (an AWS_SESSION_TOKEN or AWS_PROFILE next to the
+// profile's access key).
+var credentialEnvPrefixes = []string{"AWS_", "ARM_", "AZURE_", "GOOGLE_", "CLOUDSDK_"}
+
+func isCredentialEnv(name string) bool {
+	for _, prefix := range credentialEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dotEnv holds the variables of .env. Like a profile's, they are only passed
+// to child processes and never set in the provisioner's own environment.
+var dotEnv map[string]string
+
+// baseEnv returns the parent environment with the variables of .env it does
+// not set itself.
+func baseEnv() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(dotEnv))
+	for k := range dotEnv {
+		if _, ok := os.LookupEnv(k); !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+dotEnv[k])
+	}
+	return env
+}
+
+// profileEnv returns the environment for child tofu processes: the parent
+// environment and .env without their provider settings, overlaid with the
+// profile's variables. The parent environment itself is never modified. An
+// empty name selects the parent environment and .env.
+func profileEnv(rootPath, name string) ([]string, error) {
+	if name == "" {
+		return baseEnv(), nil
+	}
+
+	vars, err := loadProfile(rootPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, entry := range baseEnv() {
+		if k, _, _ := strings.Cut(entry, "="); !isCredentialEnv(k) {
+			env = append(env, entry)
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env, nil
+}
+
+// envLookup resolves variables the way exec does: later entries win.
+func envLookup(env []string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		for i := len(env) - 1; i >= 0; i-- {
+			if k, v, ok := strings.Cut(env[i], "="); ok && k == name {
+				return v, true
+			}
+		}
+		return "", false
+	}
+}
+
+func profileLabel(name string) string {
+	if name == "" {
+		return "(environment)"
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileEnv(t *testing.T) {
+	root := t.TempDir()
+	profiles := `{
+  "dev": {"AWS_ACCESS_KEY_ID": "dev-key", "AWS_SECRET_ACCESS_KEY": "dev-secret"},
+  "prod": {"ARM_CLIENT_ID": "prod-client"}
+}`
+	if err := os.WriteFile(filepath.Join(root, profilesFile), []byte(profiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "parent-key")
+	t.Setenv("AWS_SESSION_TOKEN", "parent-token")
+	t.Setenv("ARM_TENANT_ID", "parent-tenant")
+	t.Setenv("PROVISIONER_TEST_SETTING", "kept")
+	dotEnv = map[string]string{"AWS_PROVISIONER_TEST_REGION": "eu-west-1", "PROVISIONER_TEST_DOTENV": "dotenv", "PROVISIONER_TEST_SETTING": "overridden"}
+	t.Cleanup(func() { dotEnv = nil })
+
+	tests := []struct {
+		profile string
+		want    map[string]string
+		unset   []string
+		err     string
+	}{
+		{profile: "", want: map[string]string{"AWS_ACCESS_KEY_ID": "parent-key", "AWS_SESSION_TOKEN": "parent-token", "AWS_PROVISIONER_TEST_REGION": "eu-west-1", "PROVISIONER_TEST_SETTING": "kept", "PROVISIONER_TEST_DOTENV": "dotenv"}},
+		{profile: "dev", want: map[string]string{"AWS_ACCESS_KEY_ID": "dev-key", "AWS_SECRET_ACCESS_KEY": "dev-secret", "PROVISIONER_TEST_SETTING": "kept", "PROVISIONER_TEST_DOTENV": "dotenv"}, unset: []string{"AWS_SESSION_TOKEN", "ARM_TENANT_ID", "AWS_PROVISIONER_TEST_REGION"}},
+		{profile: "prod", want: map[string]string{"ARM_CLIENT_ID": "prod-client"}, unset: []string{"AWS_ACCESS_KEY_ID", "ARM_TENANT_ID", "AWS_PROVISIONER_TEST_REGION"}},
+		{profile: "staging", err: `credential profile "staging" not found in ` + filepath.Join(root, profilesFile) + ` (available: dev, prod)`},
+	}
+	for _, tt := range tests {
+		env, err := profileEnv(root, tt.profile)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("profile %q: error %v, want %q", tt.profile, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("profile %q: %v", tt.profile, err)
+			continue
+		}
+
+		lookup := envLookup(env)
+		for name, want := range tt.want {
+			if got, _ := lookup(name); got != want {
+				t.Errorf("profile %q: %s = %q, want %q", tt.profile, name, got, want)
+			}
+		}
+		for _, name := range tt.unset {
+			if value, ok := lookup(name); ok {
+				t.Errorf("profile %q: %s = %q leaked from the parent environment", tt.profile, name, value)
+			}
+		}
+	}
+
+	// The parent environment is left alone, and .env only reaches children
+	if got := os.Getenv("AWS_ACCESS_KEY_ID"); got != "parent-key" {
+		t.Errorf("parent AWS_ACCESS_KEY_ID changed to %q", got)
+	}
+	if value, ok := os.LookupEnv("PROVISIONER_TEST_DOTENV"); ok {
+		t.Errorf("PROVISIONER_TEST_DOTENV = %q leaked into the parent environment", value)
+	}
+}
+
+func TestProfileEnvWithoutProfiles(t *testing.T) {
+	if _, err := profileEnv(t.TempDir(), "dev"); err == nil || !strings.Contains(err.Error(), "error loading credential profiles") {
+		t.Errorf("got %v, want an error loading the profiles", err)
+	}
+}
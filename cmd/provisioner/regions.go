@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"multicloud-iac-provisioner/pkg/config"
+)
+
+// runRegions lists the regions and zones of the offline region catalog for
+// the given providers (all when empty).
+func runRegions(rootPath string, providers []string) error {
+	catalog, err := config.LoadRegionCatalog(rootPath)
+	if err != nil {
+		return err
+	}
+
+	if len(providers) == 0 {
+		providers = supportedProviders
+	}
+	for _, provider := range providers {
+		if _, ok := catalog.Providers[provider]; !ok {
+			return fmt.Errorf("unknown provider %q (expected one of: %s)", provider, strings.Join(supportedProviders, ", "))
+		}
+	}
+
+	fmt.Printf("Region catalog %s\n", catalog.Version)
+	for _, provider := range providers {
+		fmt.Printf("\n%s:\n", credentialChecks[provider].DisplayName)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  REGION\tNAME\tZONES")
+		for _, id := range catalog.Regions(provider) {
+			info := catalog.Providers[provider][id]
+			zones := strings.Join(info.Zones, ", ")
+			if zones == "" {
+				zones = "-"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", id, info.Name, zones)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"multicloud-iac-provisioner/pkg/sshkey"
+)
+
+// moduleSourceRe matches the module source written into a resource's main.tf.
+var moduleSourceRe = regexp.MustCompile(`source\s*=\s*"([^"]+)"`)
+
+// connectionStringRe extracts user and host from an ssh_connection_string output.
+var connectionStringRe = regexp.MustCompile(`(\S+)@(\S+)\s*$`)
+
+// shellSafeRe matches arguments that can be printed without quoting.
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9@%+=:,./_-]+$`)
+
+type sshTarget struct {
+	User    string
+	Host    string
+	KeyPath string
+}
+
+// resourceModuleSource returns the module path referenced by a generated main.tf.
+func resourceModuleSource(resourceDir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(resourceDir, "main.tf"))
+	if err != nil {
+		return "", err
+	}
+	match := moduleSourceRe.FindStringSubmatch(string(content))
+	if match == nil {
+		return "", fmt.Errorf("no module source found in %s", filepath.Join(resourceDir, "main.tf"))
+	}
+	return match[1], nil
+}
+
+func outputString(outputs map[string]TofuOutput, names ...string) string {
+	for _, name := range names {
+		out, ok := outputs[name]
+		if !ok {
+			continue
+		}
+		if s, ok := out.Value.(string); ok && s != "" && s != "None" {
+			return s
+		}
+	}
+	return ""
+}
+
+// findPrivateKey looks for the generated <instance>.pem next to the resource
+// state first and then in the module directory older module versions wrote it to.
+// An empty path means no generated key exists and ssh falls back to its own
+// identities (e.g. when the config supplied ssh_public_key).
+func findPrivateKey(resourceDir, instance string) string {
+	candidates := []string{sshkey.PrivateKeyPath(resourceDir, instance)}
+	if source, err := resourceModuleSource(resourceDir); err == nil {
+		candidates = append(candidates, filepath.Join(source, instance+".pem"))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// checkKeyPermissions mirrors the check ssh performs itself so the user gets
+// an actionable message instead of "UNPROTECTED PRIVATE KEY FILE".
+func checkKeyPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("permissions %#o for %s are too open; run: chmod 600 %s", perm, path, path)
+	}
+	return nil
+}
+
+func resolveSSHTarget(provisionDir, instance string) (*sshTarget, error) {
+	absProvisionDir, err := filepath.Abs(provisionDir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path: %w", err)
+	}
+
+	resourceDir := filepath.Join(absProvisionDir, instance)
+	if info, err := os.Stat(resourceDir); err != nil || !info.IsDir() {
+		var available []string
+		entries, _ := os.ReadDir(absProvisionDir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				available = append(available, entry.Name())
+			}
+		}
+		return nil, fmt.Errorf("instance %q not found in %s (available: %s)", instance, absProvisionDir, strings.Join(available, ", "))
+	}
+
+	outputs, err := readOutputs(resourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading outputs for %s: %w", instance, err)
+	}
+
+	target := &sshTarget{
+		User: outputString(outputs, "ssh_user"),
+		Host: outputString(outputs, "public_ip", "external_ip"),
+	}
+
+	// Fall back to the connection string for state created before ssh_user existed
+	if target.User == "" || target.Host == "" {
+		if match := connectionStringRe.FindStringSubmatch(outputString(outputs, "ssh_connection_string")); match != nil {
+			if target.User == "" {
+				target.User = match[1]
+			}
+			if target.Host == "" {
+				target.Host = match[2]
+			}
+		}
+	}
+
+	if target.Host == "" {
+		return nil, fmt.Errorf("no public IP found in outputs of %s; is it a compute.instance?", instance)
+	}
+	if target.User == "" {
+		return nil, fmt.Errorf("no SSH user found in outputs of %s", instance)
+	}
+
+	target.KeyPath = findPrivateKey(resourceDir, instance)
+	if target.KeyPath != "" {
+		if err := checkKeyPermissions(target.KeyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return target, nil
+}
+
+// sshArgs builds the ssh argument list: options go before the destination and
+// everything after "--" is passed as the remote command.
+func sshArgs(target *sshTarget, extraArgs []string) []string {
+	var options, command []string
+	for i, arg := range extraArgs {
+		if arg == "--" {
+			command = extraArgs[i+1:]
+			break
+		}
+		options = append(options, arg)
+	}
+
+	var args []string
+	if target.KeyPath != "" {
+		args = append(args, "-i", target.KeyPath)
+	}
+	args = append(args, options...)
+	args = append(args, fmt.Sprintf("%s@%s", target.User, target.Host))
+	return append(args, command...)
+}
+
+func shellQuote(arg string) string {
+	if shellSafeRe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func runSSH(provisionDir, instance string, extraArgs []string, printOnly bool) error {
+	target, err := resolveSSHTarget(provisionDir, instance)
+	if err != nil {
+		return err
+	}
+
+	args := sshArgs(target, extraArgs)
+
+	if printOnly {
+		quoted := []string{"ssh"}
+		for _, arg := range args {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		fmt.Println(strings.Join(quoted, " "))
+		return nil
+	}
+
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("ssh client not found on PATH: %w", err)
+	}
+
+	cmd := exec.Command(sshPath, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
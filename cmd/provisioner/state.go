@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"multicloud-iac-provisioner/pkg/config"
+)
+
+// stateFile is written into every provisioning directory so later commands
+// run with the same settings the resources were created with.
+const stateFile = ".provisioner.json"
+
+type provisionState struct {
+	CredentialsProfile string          `json:"credentials_profile,omitempty"`
+	Environment        string          `json:"environment,omitempty"`
+	Resources          []resourceState `json:"resources,omitempty"`
+}
+
+// resourceState records where a resource of the directory was provisioned,
+// since a directory may hold resources of several providers.
+type resourceState struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Provider string `json:"provider"`
+	Region   string `json:"region,omitempty"`
+}
+
+// resource returns the recorded resource with the given ID, or nil.
+func (s *provisionState) resource(id string) *resourceState {
+	for i := range s.Resources {
+		if s.Resources[i].ID == id {
+			return &s.Resources[i]
+		}
+	}
+	return nil
+}
+
+func readState(provisionDir string) (*provisionState, error) {
+	state := &provisionState{}
+	data, err := os.ReadFile(filepath.Join(provisionDir, stateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading provisioning state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing provisioning state: %w", err)
+	}
+	return state, nil
+}
+
+func writeState(provisionDir string, state *provisionState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(provisionDir, stateFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing provisioning state: %w", err)
+	}
+	return nil
+}
+
+// destroyOrder returns the resource directories in the order they are
+// destroyed: the reverse of the provisioning order recorded in state, so
+// networks outlive the instances in them. Directories missing from the state
+// go first.
+func destroyOrder(state *provisionState, dirs []string) []string {
+	var order []string
+	for _, dir := range dirs {
+		if state.resource(dir) == nil {
+			order = append(order, dir)
+		}
+	}
+	for i := len(state.Resources) - 1; i >= 0; i-- {
+		if containsDir(dirs, state.Resources[i].ID) {
+			order = append(order, state.Resources[i].ID)
+		}
+	}
+	return order
+}
+
+func containsDir(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if d == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// orphanedResources returns the resources recorded in state that are still
+// provisioned on disk but no longer part of plan.
+func orphanedResources(state *provisionState, plan *config.ProvisioningPlan) []resourceState {
+	planned := map[string]bool{}
+	for _, res := range plan.Resources {
+		planned[res.ID] = true
+	}
+
+	var orphans []resourceState
+	for _, res := range state.Resources {
+		if planned[res.ID] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(plan.OutputDir, res.ID)); err == nil {
+			orphans = append(orphans, res)
+		}
+	}
+	return orphans
+}
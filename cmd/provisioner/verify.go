@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,41 +9,73 @@ import (
 	"strings"
 )
 
-func runTofuSilent(dir string, args ...string) error {
+type credentialCheck struct {
+	DisplayName string
+	TfContent   string
+}
+
+// credentialChecks holds a minimal configuration per provider whose plan only
+// succeeds with valid credentials.
+var credentialChecks = map[string]credentialCheck{
+	"aws": {
+		DisplayName: "AWS",
+		TfContent:   "\n\t\tprovider \"aws\" {\n\t\t\tregion = \"us-east-1\"\n\t\t}\n\t\tdata \"aws_caller_identity\" \"current\" {}\n\t",
+	},
+	"azure": {
+		DisplayName: "Azure",
+		TfContent:   "\n\t\tprovider \"azurerm\" {\n\t\t\tfeatures {} \n\t\t}\n\t\tdata \"azurerm_client_config\" \"current\" {}\n\t",
+	},
+	"gcp": {
+		DisplayName: "GCP",
+		TfContent:   "\n\t\tprovider \"google\" {\n\t\t\tregion = \"us-central1\"\n\t\t}\n\t\tdata \"google_client_config\" \"current\" {}\n\t",
+	},
+}
+
+// supportedProviders is the order providers are checked and reported in.
+var supportedProviders = []string{"aws", "azure", "gcp"}
+
+type verifyResult struct {
+	Provider string `json:"provider"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+func runTofuSilent(dir string, env []string, args ...string) error {
 	cmd := exec.Command("tofu", args...)
 	cmd.Dir = dir
+	cmd.Env = env
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		if len(output) == 0 {
+			return err
+		}
 		return fmt.Errorf("%s", string(output))
 	}
 	return nil
 }
 
-func verifyProvider(name string, tfContent string) {
-	fmt.Printf("Testing %s credentials... ", name)
+func verifyProvider(provider string, env []string) error {
+	check := credentialChecks[provider]
 
 	// Create temp dir
-	tmpDir, err := os.MkdirTemp("", "verify_creds_"+name)
+	tmpDir, err := os.MkdirTemp("", "verify_creds_"+provider)
 	if err != nil {
-		fmt.Printf("❌ Error creating temp dir: %v\n", err)
-		return
+		return fmt.Errorf("error creating temp dir: %w", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
 	// Write main.tf
-	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(tfContent), 0644); err != nil {
-		fmt.Printf("❌ Error writing main.tf: %v\n", err)
-		return
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(check.TfContent), 0644); err != nil {
+		return fmt.Errorf("error writing main.tf: %w", err)
 	}
 
 	// Init
-	if err := runTofuSilent(tmpDir, "init"); err != nil {
-		fmt.Printf("❌ Init failed:\n%v\n", err)
-		return
+	if err := runTofuSilent(tmpDir, env, "init"); err != nil {
+		return fmt.Errorf("init failed: %s", strings.TrimSpace(err.Error()))
 	}
 
 	// Plan
-	if err := runTofuSilent(tmpDir, "plan"); err != nil {
+	if err := runTofuSilent(tmpDir, env, "plan"); err != nil {
 		// Clean up error message for display
 		msg := err.Error()
 		if strings.Contains(msg, "Error:") {
@@ -51,20 +84,80 @@ func verifyProvider(name string, tfContent string) {
 				msg = strings.TrimSpace(parts[1])
 			}
 		}
-		fmt.Printf("❌ Plan failed: %s\n", msg)
-		return
+		return fmt.Errorf("plan failed: %s", msg)
 	}
 
-	fmt.Println("✅ Success!")
+	return nil
+}
+
+// selectProviders validates the providers to check and removes duplicates,
+// keeping the order they are given in. No providers selects all of them.
+func selectProviders(providers []string) ([]string, error) {
+	if len(providers) == 0 {
+		return supportedProviders, nil
+	}
+
+	seen := map[string]bool{}
+	var selected []string
+	for _, provider := range providers {
+		if _, ok := credentialChecks[provider]; !ok {
+			return nil, fmt.Errorf("unknown provider %q (expected one of: %s)", provider, strings.Join(supportedProviders, ", "))
+		}
+		if !seen[provider] {
+			seen[provider] = true
+			selected = append(selected, provider)
+		}
+	}
+	return selected, nil
 }
 
-func runVerifyCreds() {
-	// 1. AWS
-	verifyProvider("AWS", "\n\t\tprovider \"aws\" {\n\t\t\tregion = \"us-east-1\"\n\t\t}\n\t\tdata \"aws_caller_identity\" \"current\" {}\n\t")
+// runVerifyCreds checks the given providers (all when empty) using env for
+// the tofu processes and returns an error if any of them fails. format is
+// either "text" or "json".
+func runVerifyCreds(providers []string, format string, env []string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %q (expected text or json)", format)
+	}
+
+	selected, err := selectProviders(providers)
+	if err != nil {
+		return err
+	}
+
+	var results []verifyResult
+	var failed []string
+	for _, provider := range selected {
+		if format == "text" {
+			fmt.Printf("Testing %s credentials... ", credentialChecks[provider].DisplayName)
+		}
+
+		result := verifyResult{Provider: provider, OK: true}
+		if err := verifyProvider(provider, env); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			failed = append(failed, provider)
+		}
+		results = append(results, result)
 
-	// 2. Azure
-	verifyProvider("Azure", "\n\t\tprovider \"azurerm\" {\n\t\t\tfeatures {} \n\t\t}\n\t\tdata \"azurerm_client_config\" \"current\" {}\n\t")
+		if format == "text" {
+			if result.OK {
+				fmt.Println("✅ Success!")
+			} else {
+				fmt.Printf("❌ %s\n", result.Error)
+			}
+		}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return fmt.Errorf("error encoding results: %w", err)
+		}
+	}
 
-	// 3. GCP
-	verifyProvider("GCP", "\n\t\tprovider \"google\" {\n\t\t\tregion = \"us-central1\"\n\t\t}\n\t\tdata \"google_client_config\" \"current\" {}\n\t")
+	if len(failed) > 0 {
+		return fmt.Errorf("credential verification failed for: %s", strings.Join(failed, ", "))
+	}
+	return nil
 }
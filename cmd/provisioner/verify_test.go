@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectProviders(t *testing.T) {
+	tests := []struct {
+		providers []string
+		want      []string
+		err       string
+	}{
+		{nil, []string{"aws", "azure", "gcp"}, ""},
+		{[]string{"gcp"}, []string{"gcp"}, ""},
+		{[]string{"gcp", "aws", "gcp"}, []string{"gcp", "aws"}, ""},
+		{[]string{"aws", "oracle"}, nil, `unknown provider "oracle" (expected one of: aws, azure, gcp)`},
+		{[]string{"AWS"}, nil, `unknown provider "AWS" (expected one of: aws, azure, gcp)`},
+	}
+	for _, tt := range tests {
+		got, err := selectProviders(tt.providers)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("selectProviders(%q): error %v, want %q", tt.providers, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectProviders(%q) = %q, %v, want %q", tt.providers, got, err, tt.want)
+		}
+	}
+}
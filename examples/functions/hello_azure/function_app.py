@@ -0,0 +1,13 @@
+import json
+import os
+
+import azure.functions as func
+
+app = func.FunctionApp(http_auth_level=func.AuthLevel.ANONYMOUS)
+
+
+@app.route(route="hello")
+def hello(req: func.HttpRequest) -> func.HttpResponse:
+    name = req.params.get("name", "world")
+    body = json.dumps({"message": f"{os.environ.get('GREETING', 'Hello')}, {name}!"})
+    return func.HttpResponse(body, mimetype="application/json")
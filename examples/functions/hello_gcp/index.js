@@ -0,0 +1,4 @@
+exports.handler = (req, res) => {
+  const name = req.query.name || 'world';
+  res.json({ message: `${process.env.GREETING || 'Hello'}, ${name}!` });
+};
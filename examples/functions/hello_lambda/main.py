@@ -0,0 +1,11 @@
+import json
+import os
+
+
+def handler(event, context):
+    name = (event.get("queryStringParameters") or {}).get("name", "world")
+    return {
+        "statusCode": 200,
+        "headers": {"Content-Type": "application/json"},
+        "body": json.dumps({"message": f"{os.environ.get('GREETING', 'Hello')}, {name}!"}),
+    }
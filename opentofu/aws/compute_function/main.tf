@@ -0,0 +1,56 @@
+resource "aws_iam_role" "function" {
+  name = "${var.function_id}-role"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "lambda.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+  tags = var.metadata
+}
+
+# Lets the function write its logs to CloudWatch
+resource "aws_iam_role_policy_attachment" "logs" {
+  role       = aws_iam_role.function.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_lambda_function" "function" {
+  function_name    = var.function_id
+  role             = aws_iam_role.function.arn
+  runtime          = var.runtime
+  handler          = var.entry_point
+  filename         = var.source_zip
+  source_code_hash = filebase64sha256(var.source_zip)
+  memory_size      = var.memory_mb
+  timeout          = var.timeout_seconds
+
+  dynamic "environment" {
+    for_each = length(var.environment) > 0 ? [1] : []
+    content {
+      variables = var.environment
+    }
+  }
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+
+  depends_on = [aws_iam_role_policy_attachment.logs]
+}
+
+# A public HTTPS endpoint; the function handles authentication itself
+resource "aws_lambda_function_url" "url" {
+  function_name      = aws_lambda_function.function.function_name
+  authorization_type = "NONE"
+}
+
+resource "aws_lambda_permission" "url" {
+  statement_id           = "FunctionURLAllowPublicAccess"
+  action                 = "lambda:InvokeFunctionUrl"
+  function_name          = aws_lambda_function.function.function_name
+  principal              = "*"
+  function_url_auth_type = "NONE"
+}
@@ -0,0 +1,12 @@
+# The invoke URL without a trailing slash, like on GCP and Azure
+output "url" {
+  value = trimsuffix(aws_lambda_function_url.url.function_url, "/")
+}
+
+output "function_name" {
+  value = aws_lambda_function.function.function_name
+}
+
+output "function_arn" {
+  value = aws_lambda_function.function.arn
+}
@@ -0,0 +1,48 @@
+variable "region" {
+  description = "AWS Region (e.g., us-east-1, eu-central-1)."
+  type        = string
+}
+
+variable "function_id" {
+  description = "Name of the Lambda function."
+  type        = string
+}
+
+variable "runtime" {
+  description = "Lambda runtime (resolved from parser/catalog.json by the provisioner)."
+  type        = string
+}
+
+variable "entry_point" {
+  description = "Handler called on requests, <file>.<function> (e.g. main.handler)."
+  type        = string
+}
+
+variable "source_zip" {
+  description = "Path of the zip archive with the function code, packaged by the provisioner."
+  type        = string
+}
+
+variable "memory_mb" {
+  description = "Memory in MB."
+  type        = number
+  default     = 256
+}
+
+variable "timeout_seconds" {
+  description = "Request timeout in seconds."
+  type        = number
+  default     = 60
+}
+
+variable "environment" {
+  description = "Environment variables of the function."
+  type        = map(string)
+  default     = {}
+}
+
+variable "metadata" {
+  description = "Tags applied to the function."
+  type        = map(string)
+  default     = {}
+}
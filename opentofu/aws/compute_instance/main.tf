@@ -1,112 +1,130 @@
 locals {
-  size_map = {
-    "small"  = "t3.micro"       # 2 vCPU, 1 GB RAM
-    "medium" = "t3.small"       # 2 vCPU, 4 GB RAM
-    "large"  = "m7i-flex.large" # 2 vCPU, 8 GB RAM
-  }
-
-  ami_id = var.os == "ubuntu" ? data.aws_ami.ubuntu.id : data.aws_ami.debian.id
-
-  ssh_user = var.os == "ubuntu" ? "ubuntu" : "admin"
-}
+  ssh_user = var.ssh_user
 
-data "aws_ami" "ubuntu" {
-  most_recent = true
-  owners      = ["099720109477"] 
-  filter {
-    name   = "name"
-    values = ["ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*"]
-  }
+  # Instances that don't join a network.vpc get a private network
+  own_network = var.subnet_id == null
+  vpc_id      = local.own_network ? aws_vpc.vpc[0].id : var.vpc_id
+  subnet_id   = local.own_network ? aws_subnet.subnet[0].id : var.subnet_id
 }
 
-data "aws_ami" "debian" {
+# The image is resolved from parser/catalog.json by the provisioner, unless
+# the service names an AMI directly
+data "aws_ami" "image" {
+  count       = var.image.id == null ? 1 : 0
   most_recent = true
-  owners      = ["136693071363"] 
+  owners      = [var.image.owner]
   filter {
     name   = "name"
-    values = ["debian-12-amd64-*"]
+    values = [var.image.name]
   }
 }
 
-resource "tls_private_key" "gen" {
-  count     = var.ssh_public_key == "" ? 1 : 0
-  algorithm = "RSA"
-  rsa_bits  = 4096
-}
-
 resource "aws_key_pair" "auth" {
   key_name   = "${var.instance_id}-key"
-  public_key = var.ssh_public_key != "" ? var.ssh_public_key : tls_private_key.gen[0].public_key_openssh
+  public_key = var.ssh_public_key
 }
 
 resource "aws_vpc" "vpc" {
+  count      = local.own_network ? 1 : 0
   cidr_block = "10.0.0.0/16"
   tags       = merge(var.metadata, { Name = "${var.instance_id}-vpc" })
 }
 
 resource "aws_internet_gateway" "igw" {
-  vpc_id = aws_vpc.vpc.id
+  count  = local.own_network ? 1 : 0
+  vpc_id = aws_vpc.vpc[0].id
   tags   = { Name = "${var.instance_id}-igw" }
 }
 
 resource "aws_subnet" "subnet" {
-  vpc_id                  = aws_vpc.vpc.id
+  count                   = local.own_network ? 1 : 0
+  vpc_id                  = aws_vpc.vpc[0].id
   cidr_block              = "10.0.1.0/24"
   map_public_ip_on_launch = true # Auto-assign public IP
   tags                    = { Name = "${var.instance_id}-subnet" }
 }
 
 resource "aws_route_table" "rt" {
-  vpc_id = aws_vpc.vpc.id
+  count  = local.own_network ? 1 : 0
+  vpc_id = aws_vpc.vpc[0].id
   route {
     cidr_block = "0.0.0.0/0"
-    gateway_id = aws_internet_gateway.igw.id
+    gateway_id = aws_internet_gateway.igw[0].id
   }
 }
 
 resource "aws_route_table_association" "a" {
-  subnet_id      = aws_subnet.subnet.id
-  route_table_id = aws_route_table.rt.id
+  count          = local.own_network ? 1 : 0
+  subnet_id      = aws_subnet.subnet[0].id
+  route_table_id = aws_route_table.rt[0].id
+}
+
+# The private network resources became conditional
+moved {
+  from = aws_vpc.vpc
+  to   = aws_vpc.vpc[0]
+}
+
+moved {
+  from = aws_internet_gateway.igw
+  to   = aws_internet_gateway.igw[0]
+}
+
+moved {
+  from = aws_subnet.subnet
+  to   = aws_subnet.subnet[0]
+}
+
+moved {
+  from = aws_route_table.rt
+  to   = aws_route_table.rt[0]
+}
+
+moved {
+  from = aws_route_table_association.a
+  to   = aws_route_table_association.a[0]
 }
 
 resource "aws_security_group" "sg" {
   name   = "${var.instance_id}-sg"
-  vpc_id = aws_vpc.vpc.id
-
-  ingress {
-    from_port   = 22
-    to_port     = 22
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]
-  }
+  vpc_id = local.vpc_id
 
+  # Rules are translated for security groups by the provisioner
   dynamic "ingress" {
-    for_each = var.allowed_ports
+    for_each = [for r in var.firewall_rules : r if !r.egress]
     content {
-      from_port   = ingress.value
-      to_port     = ingress.value
-      protocol    = "tcp"
-      cidr_blocks = ["0.0.0.0/0"]
+      description = ingress.value.description
+      from_port   = ingress.value.from_port
+      to_port     = ingress.value.to_port
+      protocol    = ingress.value.protocol
+      cidr_blocks = ingress.value.cidr_blocks
     }
   }
 
-  egress {
-    from_port   = 0
-    to_port     = 0
-    protocol    = "-1"
-    cidr_blocks = ["0.0.0.0/0"]
+  dynamic "egress" {
+    for_each = [for r in var.firewall_rules : r if r.egress]
+    content {
+      description = egress.value.description
+      from_port   = egress.value.from_port
+      to_port     = egress.value.to_port
+      protocol    = egress.value.protocol
+      cidr_blocks = egress.value.cidr_blocks
+    }
   }
 }
 
 resource "aws_instance" "vm" {
-  ami           = local.ami_id
-  instance_type = local.size_map[var.size]
+  ami           = var.image.id != null ? var.image.id : data.aws_ami.image[0].id
+  instance_type = var.machine_type
   key_name      = aws_key_pair.auth.key_name
 
-  subnet_id                   = aws_subnet.subnet.id
+  subnet_id                   = local.subnet_id
   vpc_security_group_ids      = [aws_security_group.sg.id]
   associate_public_ip_address = true
 
+  user_data                   = var.user_data
+  user_data_replace_on_change = true
+
   root_block_device {
     volume_size = var.disk_size_gb
     volume_type = "gp3" # General Purpose SSD
@@ -117,10 +135,3 @@ resource "aws_instance" "vm" {
     managed_by = "sky-control"
   })
 }
-
-resource "local_file" "private_key" {
-  count           = var.ssh_public_key == "" ? 1 : 0
-  content         = tls_private_key.gen[0].private_key_pem
-  filename        = "${path.module}/${var.instance_id}.pem"
-  file_permission = "0600"
-}
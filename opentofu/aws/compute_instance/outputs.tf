@@ -11,5 +11,9 @@ output "private_ip" {
 }
 
 output "ssh_connection_string" {
-  value = var.ssh_public_key == "" ? "ssh -i ${var.instance_id}.pem ${local.ssh_user}@${aws_instance.vm.public_ip}" : "ssh ${local.ssh_user}@${aws_instance.vm.public_ip}"
+  value = var.private_key_path != "" ? "ssh -i ${var.private_key_path} ${local.ssh_user}@${aws_instance.vm.public_ip}" : "ssh ${local.ssh_user}@${aws_instance.vm.public_ip}"
+}
+
+output "ssh_user" {
+  value = local.ssh_user
 }
@@ -8,25 +8,29 @@ variable "instance_id" {
   type        = string
 }
 
-variable "size" {
-  description = "small, medium, large."
+variable "machine_type" {
+  description = "EC2 instance type (resolved from the size in parser/catalog.json)."
   type        = string
+}
+
+variable "image" {
+  description = "AMI ID, or owner and name filter of the latest matching AMI (resolved from the OS in parser/catalog.json)."
+  type = object({
+    id    = optional(string)
+    owner = optional(string)
+    name  = optional(string)
+  })
 
   validation {
-    condition     = contains(["small", "medium", "large"], var.size)
-    error_message = "Size must be: small, medium, or large."
+    condition     = var.image.id != null || (var.image.owner != null && var.image.name != null)
+    error_message = "image needs either an id or an owner and name filter."
   }
 }
 
-variable "os" {
-  description = "OS Family: ubuntu, debian."
+variable "ssh_user" {
+  description = "Default login user of the image."
   type        = string
   default     = "ubuntu"
-
-  validation {
-    condition     = contains(["ubuntu", "debian"], var.os)
-    error_message = "OS must be: ubuntu or debian."
-  }
 }
 
 variable "disk_size_gb" {
@@ -42,13 +46,57 @@ variable "metadata" {
 }
 
 variable "ssh_public_key" {
-  description = "SSH Public Key string. If empty, one will be generated."
+  description = "SSH Public Key string. Generated per project by the provisioner if not configured."
+  type        = string
+}
+
+variable "private_key_path" {
+  description = "Path of the private key matching ssh_public_key, used in the connection string."
   type        = string
   default     = ""
 }
 
-variable "allowed_ports" {
-  description = "List of ports to allow ingress traffic on."
-  type        = list(number)
-  default     = []
-}
\ No newline at end of file
+variable "firewall_rules" {
+  description = "Security group rules (translated from the service's firewall settings by the provisioner)."
+  type = list(object({
+    description = string
+    egress      = bool
+    protocol    = string
+    from_port   = number
+    to_port     = number
+    cidr_blocks = list(string)
+  }))
+  default = [{
+    description = "SSH"
+    egress      = false
+    protocol    = "tcp"
+    from_port   = 22
+    to_port     = 22
+    cidr_blocks = ["0.0.0.0/0"]
+    }, {
+    description = "All outbound traffic"
+    egress      = true
+    protocol    = "-1"
+    from_port   = 0
+    to_port     = 0
+    cidr_blocks = ["0.0.0.0/0"]
+  }]
+}
+
+variable "user_data" {
+  description = "Cloud-init user data rendered by the provisioner."
+  type        = string
+  default     = null
+}
+
+variable "vpc_id" {
+  description = "VPC of the network.vpc the instance joins; a private VPC is created when subnet_id is null."
+  type        = string
+  default     = null
+}
+
+variable "subnet_id" {
+  description = "Subnet of the network.vpc the instance joins."
+  type        = string
+  default     = null
+}
@@ -0,0 +1,128 @@
+# Clusters live in the default VPC, with nodes in its default subnets
+data "aws_vpc" "default" {
+  default = true
+}
+
+data "aws_subnets" "default" {
+  filter {
+    name   = "vpc-id"
+    values = [data.aws_vpc.default.id]
+  }
+
+  filter {
+    name   = "default-for-az"
+    values = ["true"]
+  }
+}
+
+resource "aws_iam_role" "cluster" {
+  name = "${var.cluster_id}-cluster"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "eks.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+  tags = var.metadata
+}
+
+resource "aws_iam_role_policy_attachment" "cluster" {
+  role       = aws_iam_role.cluster.name
+  policy_arn = "arn:aws:iam::aws:policy/AmazonEKSClusterPolicy"
+}
+
+resource "aws_iam_role" "nodes" {
+  name = "${var.cluster_id}-nodes"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "ec2.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+  tags = var.metadata
+}
+
+resource "aws_iam_role_policy_attachment" "nodes" {
+  for_each = toset([
+    "arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+    "arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
+    "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+  ])
+
+  role       = aws_iam_role.nodes.name
+  policy_arn = each.value
+}
+
+resource "aws_eks_cluster" "cluster" {
+  name     = var.cluster_id
+  role_arn = aws_iam_role.cluster.arn
+  version  = var.kubernetes_version
+
+  vpc_config {
+    subnet_ids = data.aws_subnets.default.ids
+  }
+
+  # The identity running the provisioner administers the cluster
+  access_config {
+    authentication_mode                         = "API_AND_CONFIG_MAP"
+    bootstrap_cluster_creator_admin_permissions = true
+  }
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+
+  depends_on = [aws_iam_role_policy_attachment.cluster]
+}
+
+resource "aws_eks_node_group" "nodes" {
+  cluster_name    = aws_eks_cluster.cluster.name
+  node_group_name = "default"
+  node_role_arn   = aws_iam_role.nodes.arn
+  subnet_ids      = data.aws_subnets.default.ids
+  instance_types  = [var.machine_type]
+
+  scaling_config {
+    desired_size = var.node_count
+    min_size     = var.node_count
+    max_size     = var.node_count
+  }
+
+  tags = var.metadata
+
+  depends_on = [aws_iam_role_policy_attachment.nodes]
+}
+
+# kubectl authenticates through the AWS CLI
+locals {
+  kubeconfig = yamlencode({
+    apiVersion = "v1"
+    kind       = "Config"
+    clusters = [{
+      name = var.cluster_id
+      cluster = {
+        server                       = aws_eks_cluster.cluster.endpoint
+        "certificate-authority-data" = aws_eks_cluster.cluster.certificate_authority[0].data
+      }
+    }]
+    users = [{
+      name = var.cluster_id
+      user = {
+        exec = {
+          apiVersion = "client.authentication.k8s.io/v1beta1"
+          command    = "aws"
+          args       = ["eks", "get-token", "--cluster-name", var.cluster_id, "--region", var.region]
+        }
+      }
+    }]
+    contexts = [{
+      name    = var.cluster_id
+      context = { cluster = var.cluster_id, user = var.cluster_id }
+    }]
+    "current-context" = var.cluster_id
+  })
+}
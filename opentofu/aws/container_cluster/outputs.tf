@@ -0,0 +1,17 @@
+output "endpoint" {
+  value = aws_eks_cluster.cluster.endpoint
+}
+
+output "kubernetes_version" {
+  value = aws_eks_cluster.cluster.version
+}
+
+output "node_count" {
+  value = var.node_count
+}
+
+# Written to <cluster_id>/kubeconfig by the provisioner
+output "kubeconfig" {
+  value     = local.kubeconfig
+  sensitive = true
+}
@@ -0,0 +1,32 @@
+variable "region" {
+  description = "AWS Region (e.g., us-east-1, eu-central-1)."
+  type        = string
+}
+
+variable "cluster_id" {
+  description = "Name of the EKS cluster."
+  type        = string
+}
+
+variable "machine_type" {
+  description = "Instance type of the nodes (resolved from the size catalog)."
+  type        = string
+}
+
+variable "node_count" {
+  description = "Number of nodes."
+  type        = number
+  default     = 2
+}
+
+variable "kubernetes_version" {
+  description = "Kubernetes minor version (e.g. 1.31); the EKS default when null."
+  type        = string
+  default     = null
+}
+
+variable "metadata" {
+  description = "Tags applied to the cluster and its nodes."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,64 @@
+locals {
+  port   = var.engine == "postgres" ? 5432 : 3306
+  scheme = var.engine == "postgres" ? "postgresql" : "mysql"
+}
+
+# Databases live in the default VPC; private ones are reachable from within it
+data "aws_vpc" "default" {
+  default = true
+}
+
+resource "random_password" "password" {
+  length      = 32
+  special     = false # Keeps the connection string free of escaping
+  min_lower   = 1
+  min_upper   = 1
+  min_numeric = 1
+}
+
+resource "aws_security_group" "db" {
+  name        = "${var.database_id}-db"
+  description = "Access to ${var.database_id}"
+  vpc_id      = data.aws_vpc.default.id
+
+  ingress {
+    from_port   = local.port
+    to_port     = local.port
+    protocol    = "tcp"
+    cidr_blocks = var.public_access ? var.allowed_cidrs : [data.aws_vpc.default.cidr_block]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  tags = merge(var.metadata, { Name = "${var.database_id}-db" })
+}
+
+resource "aws_db_instance" "db" {
+  identifier     = var.database_id
+  engine         = var.engine
+  engine_version = var.engine_version
+  instance_class = var.tier
+
+  allocated_storage = var.storage_gb
+  storage_type      = "gp3"
+  storage_encrypted = true
+
+  db_name  = var.database_name
+  username = var.admin_username
+  password = random_password.password.result
+
+  backup_retention_period = var.backup_retention_days
+  skip_final_snapshot     = true
+
+  publicly_accessible    = var.public_access
+  vpc_security_group_ids = [aws_security_group.db.id]
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
@@ -0,0 +1,25 @@
+output "host" {
+  value = aws_db_instance.db.address
+}
+
+output "port" {
+  value = aws_db_instance.db.port
+}
+
+output "username" {
+  value = var.admin_username
+}
+
+output "database_name" {
+  value = var.database_name
+}
+
+output "password" {
+  value     = random_password.password.result
+  sensitive = true
+}
+
+output "connection_string" {
+  value     = "${local.scheme}://${var.admin_username}:${random_password.password.result}@${aws_db_instance.db.address}:${aws_db_instance.db.port}/${var.database_name}"
+  sensitive = true
+}
@@ -0,0 +1,17 @@
+locals {
+  fqdn = var.record_name == "@" ? var.dns_zone : "${var.record_name}.${var.dns_zone}"
+}
+
+# The hosted zone is managed outside the provisioner
+data "aws_route53_zone" "zone" {
+  name         = var.dns_zone
+  private_zone = false
+}
+
+resource "aws_route53_record" "record" {
+  zone_id = data.aws_route53_zone.zone.zone_id
+  name    = local.fqdn
+  type    = var.record_type
+  ttl     = var.ttl
+  records = var.records
+}
@@ -0,0 +1,103 @@
+locals {
+  # http and https listeners get an application load balancer, tcp listeners
+  # a network load balancer
+  application  = anytrue([for l in var.listeners : l.protocol != "tcp"])
+  target_ports = distinct([for l in var.listeners : l.target_port])
+
+  attachments = {
+    for pair in setproduct(var.instance_ids, local.target_ports) :
+    "${pair[0]}-${pair[1]}" => { instance_id = pair[0], port = pair[1] }
+  }
+}
+
+# Load balancers take one subnet per availability zone
+data "aws_subnet" "network" {
+  for_each = var.subnet_ids
+  id       = each.value
+}
+
+locals {
+  subnets_by_zone = { for s in data.aws_subnet.network : s.availability_zone => s.id... }
+  subnet_ids      = [for zone, ids in local.subnets_by_zone : sort(ids)[0]]
+}
+
+resource "aws_security_group" "lb" {
+  count       = local.application ? 1 : 0
+  name        = "${var.load_balancer_id}-lb"
+  description = "Listeners of ${var.load_balancer_id}"
+  vpc_id      = var.vpc_id
+
+  dynamic "ingress" {
+    for_each = var.listeners
+    content {
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = "tcp"
+      cidr_blocks = ["0.0.0.0/0"]
+    }
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  tags = merge(var.metadata, { Name = "${var.load_balancer_id}-lb" })
+}
+
+resource "aws_lb" "lb" {
+  name               = var.load_balancer_id
+  load_balancer_type = local.application ? "application" : "network"
+  internal           = false
+  subnets            = local.subnet_ids
+  security_groups    = local.application ? [aws_security_group.lb[0].id] : null
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
+
+# One target group per backend port, named "<load_balancer_id>-<port>"
+resource "aws_lb_target_group" "backend" {
+  for_each = toset([for p in local.target_ports : tostring(p)])
+
+  name        = "${var.load_balancer_id}-${each.key}"
+  port        = tonumber(each.key)
+  protocol    = local.application ? "HTTP" : "TCP"
+  target_type = "instance"
+  vpc_id      = var.vpc_id
+
+  health_check {
+    protocol = upper(var.health_check.protocol)
+    path     = var.health_check.protocol == "http" ? var.health_check.path : null
+    port     = tostring(var.health_check.port)
+    interval = var.health_check.interval_seconds
+  }
+
+  tags = var.metadata
+}
+
+resource "aws_lb_target_group_attachment" "backend" {
+  for_each = local.attachments
+
+  target_group_arn = aws_lb_target_group.backend[tostring(each.value.port)].arn
+  target_id        = each.value.instance_id
+  port             = each.value.port
+}
+
+resource "aws_lb_listener" "listener" {
+  for_each = { for l in var.listeners : tostring(l.port) => l }
+
+  load_balancer_arn = aws_lb.lb.arn
+  port              = each.value.port
+  protocol          = upper(each.value.protocol)
+  certificate_arn   = each.value.protocol == "https" ? var.certificate_arn : null
+  ssl_policy        = each.value.protocol == "https" ? "ELBSecurityPolicy-TLS13-1-2-2021-06" : null
+
+  default_action {
+    type             = "forward"
+    target_group_arn = aws_lb_target_group.backend[tostring(each.value.target_port)].arn
+  }
+}
@@ -0,0 +1,12 @@
+# DNS name of the load balancer; point a CNAME record at it
+output "endpoint" {
+  value = aws_lb.lb.dns_name
+}
+
+output "load_balancer_arn" {
+  value = aws_lb.lb.arn
+}
+
+output "zone_id" {
+  value = aws_lb.lb.zone_id
+}
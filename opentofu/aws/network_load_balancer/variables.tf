@@ -0,0 +1,55 @@
+variable "region" {
+  description = "AWS Region (e.g., us-east-1, eu-central-1)."
+  type        = string
+}
+
+variable "load_balancer_id" {
+  description = "Name of the load balancer."
+  type        = string
+}
+
+variable "listeners" {
+  description = "Ports the load balancer accepts traffic on and the ports of the backends they forward to."
+  type = list(object({
+    port        = number
+    protocol    = string
+    target_port = number
+  }))
+}
+
+variable "health_check" {
+  description = "Health check of the backends (normalized by the provisioner)."
+  type = object({
+    protocol         = string
+    path             = string
+    port             = number
+    interval_seconds = number
+  })
+}
+
+variable "certificate_arn" {
+  description = "ACM certificate of https listeners."
+  type        = string
+  default     = null
+}
+
+variable "instance_ids" {
+  description = "EC2 instances behind the load balancer (set by the provisioner)."
+  type        = list(string)
+}
+
+variable "vpc_id" {
+  description = "VPC of the backends (set by the provisioner)."
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnets of the VPC by name (set by the provisioner)."
+  type        = map(string)
+}
+
+variable "metadata" {
+  description = "Tags applied to the load balancer."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,60 @@
+resource "aws_vpc" "vpc" {
+  cidr_block           = var.cidr
+  enable_dns_support   = true
+  enable_dns_hostnames = true
+
+  tags = merge(var.metadata, {
+    Name       = var.network_id
+    managed_by = "sky-control"
+  })
+}
+
+resource "aws_internet_gateway" "igw" {
+  vpc_id = aws_vpc.vpc.id
+  tags   = { Name = "${var.network_id}-igw" }
+}
+
+resource "aws_route_table" "rt" {
+  vpc_id = aws_vpc.vpc.id
+  route {
+    cidr_block = "0.0.0.0/0"
+    gateway_id = aws_internet_gateway.igw.id
+  }
+  tags = { Name = "${var.network_id}-rt" }
+}
+
+data "aws_availability_zones" "available" {
+  state = "available"
+}
+
+locals {
+  # Without a zone, subnets are spread across the zones of the region so that
+  # load balancers, which need subnets in two zones, can use them
+  zones = var.zone != null ? [var.zone] : data.aws_availability_zones.available.names
+}
+
+# Subnet ranges are planned by the provisioner
+resource "aws_subnet" "subnet" {
+  for_each = { for i, s in var.subnets : s.name => merge(s, { zone = local.zones[i % length(local.zones)] }) }
+
+  vpc_id                  = aws_vpc.vpc.id
+  cidr_block              = each.value.cidr
+  availability_zone       = each.value.zone
+  map_public_ip_on_launch = true # Auto-assign public IP
+
+  tags = merge(var.metadata, {
+    Name = "${var.network_id}-${each.key}"
+  })
+
+  # Subnets created before the zones were spread keep the zone AWS chose
+  lifecycle {
+    ignore_changes = [availability_zone]
+  }
+}
+
+resource "aws_route_table_association" "subnet" {
+  for_each = aws_subnet.subnet
+
+  subnet_id      = each.value.id
+  route_table_id = aws_route_table.rt.id
+}
@@ -0,0 +1,11 @@
+output "vpc_id" {
+  value = aws_vpc.vpc.id
+}
+
+output "cidr" {
+  value = aws_vpc.vpc.cidr_block
+}
+
+output "subnet_ids" {
+  value = { for name, subnet in aws_subnet.subnet : name => subnet.id }
+}
@@ -0,0 +1,34 @@
+variable "region" {
+  description = "AWS Region (e.g., us-east-1, eu-central-1)."
+  type        = string
+}
+
+variable "zone" {
+  description = "Availability zone of the subnets (spread across the zones of the region when null)."
+  type        = string
+  default     = null
+}
+
+variable "network_id" {
+  description = "Name of the VPC."
+  type        = string
+}
+
+variable "cidr" {
+  description = "Address space of the VPC (e.g. 10.1.0.0/16)."
+  type        = string
+}
+
+variable "subnets" {
+  description = "Subnets with their ranges (planned by the provisioner)."
+  type = list(object({
+    name = string
+    cidr = string
+  }))
+}
+
+variable "metadata" {
+  description = "Tags applied to the VPC and its subnets."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,100 @@
+locals {
+  stack   = split(":", var.runtime)[0]
+  version = split(":", var.runtime)[1]
+
+  # Storage account names are global and 3-24 lowercase letters and digits
+  storage_account_name = "${substr(lower(replace(var.function_id, "/[^a-zA-Z0-9]/", "")), 0, 16)}${random_id.storage.hex}"
+}
+
+resource "random_id" "storage" {
+  byte_length = 4
+}
+
+resource "azurerm_resource_group" "rg" {
+  name     = "${var.function_id}-rg"
+  location = var.region
+  tags     = var.metadata
+}
+
+# Holds the function app's state and the code packages
+resource "azurerm_storage_account" "function" {
+  name                     = local.storage_account_name
+  resource_group_name      = azurerm_resource_group.rg.name
+  location                 = azurerm_resource_group.rg.location
+  account_tier             = "Standard"
+  account_replication_type = "LRS"
+  tags                     = var.metadata
+}
+
+resource "azurerm_storage_container" "packages" {
+  name                  = "packages"
+  storage_account_id    = azurerm_storage_account.function.id
+  container_access_type = "private"
+}
+
+# The archive name carries its digest, so changed code is a new blob and a
+# new package URL, which restarts the app on the new code
+resource "azurerm_storage_blob" "package" {
+  name                   = basename(var.source_zip)
+  storage_account_name   = azurerm_storage_account.function.name
+  storage_container_name = azurerm_storage_container.packages.name
+  type                   = "Block"
+  source                 = var.source_zip
+  content_md5            = filemd5(var.source_zip)
+}
+
+# Linux Consumption apps run from a package URL; a read-only SAS grants the
+# app access to the private container
+resource "time_static" "sas" {}
+
+data "azurerm_storage_account_blob_container_sas" "package" {
+  connection_string = azurerm_storage_account.function.primary_connection_string
+  container_name    = azurerm_storage_container.packages.name
+  https_only        = true
+  start             = time_static.sas.rfc3339
+  expiry            = timeadd(time_static.sas.rfc3339, "87600h")
+
+  permissions {
+    read   = true
+    add    = false
+    create = false
+    write  = false
+    delete = false
+    list   = false
+  }
+}
+
+resource "azurerm_service_plan" "plan" {
+  name                = "${var.function_id}-plan"
+  resource_group_name = azurerm_resource_group.rg.name
+  location            = azurerm_resource_group.rg.location
+  os_type             = "Linux"
+  sku_name            = "Y1"
+  tags                = var.metadata
+}
+
+resource "azurerm_linux_function_app" "function" {
+  name                       = var.function_id
+  resource_group_name        = azurerm_resource_group.rg.name
+  location                   = azurerm_resource_group.rg.location
+  service_plan_id            = azurerm_service_plan.plan.id
+  storage_account_name       = azurerm_storage_account.function.name
+  storage_account_access_key = azurerm_storage_account.function.primary_access_key
+  https_only                 = true
+
+  site_config {
+    application_stack {
+      python_version = local.stack == "python" ? local.version : null
+      node_version   = local.stack == "node" ? local.version : null
+    }
+  }
+
+  app_settings = merge(var.environment, {
+    WEBSITE_RUN_FROM_PACKAGE               = "${azurerm_storage_blob.package.url}${data.azurerm_storage_account_blob_container_sas.package.sas}"
+    AzureFunctionsJobHost__functionTimeout = format("%02d:%02d:%02d", floor(var.timeout_seconds / 3600), floor(var.timeout_seconds % 3600 / 60), var.timeout_seconds % 60)
+  })
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
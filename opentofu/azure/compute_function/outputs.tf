@@ -0,0 +1,8 @@
+# HTTP functions are served below /api/<function name>
+output "url" {
+  value = "https://${azurerm_linux_function_app.function.default_hostname}/api/${var.entry_point}"
+}
+
+output "function_name" {
+  value = azurerm_linux_function_app.function.name
+}
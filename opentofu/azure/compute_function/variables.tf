@@ -0,0 +1,47 @@
+variable "region" {
+  description = "Azure Region (e.g. westeurope)."
+  type        = string
+}
+
+variable "function_id" {
+  description = "Name of the function app; also its host name <function_id>.azurewebsites.net."
+  type        = string
+}
+
+variable "runtime" {
+  description = "Runtime as <stack>:<version>, e.g. python:3.12 (resolved from parser/catalog.json by the provisioner)."
+  type        = string
+
+  validation {
+    condition     = contains(["python", "node"], split(":", var.runtime)[0])
+    error_message = "Runtime stack must be: python or node."
+  }
+}
+
+variable "entry_point" {
+  description = "Name of the HTTP function the URL invokes (e.g. hello)."
+  type        = string
+}
+
+variable "source_zip" {
+  description = "Path of the zip archive with the function code, packaged by the provisioner."
+  type        = string
+}
+
+variable "timeout_seconds" {
+  description = "Request timeout in seconds."
+  type        = number
+  default     = 60
+}
+
+variable "environment" {
+  description = "Environment variables (app settings) of the function app."
+  type        = map(string)
+  default     = {}
+}
+
+variable "metadata" {
+  description = "Tags applied to the function app."
+  type        = map(string)
+  default     = {}
+}
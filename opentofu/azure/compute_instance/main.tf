@@ -1,39 +1,17 @@
-locals {
-  size_map = {
-    "small"  = "Standard_B2ats_v2" # 2 vCPU, 1 GB RAM
-    "medium" = "Standard_B2als_v2" # 2 vCPU, 4 GB RAM
-    "large"  = "Standard_B2as_v2"  # 2 vCPU, 8 GB RAM
-  }
-
-  os_map = {
-    "ubuntu" = {
-      publisher = "Canonical"
-      offer     = "0001-com-ubuntu-server-jammy"
-      sku       = "22_04-lts"
-      version   = "latest"
-    }
-    "debian" = {
-      publisher = "Debian"
-      offer     = "debian-11"
-      sku       = "11"
-      version   = "latest"
-    }
-  }
-}
-
-resource "tls_private_key" "example" {
-  count     = var.ssh_public_key == "" ? 1 : 0
-  algorithm = "RSA"
-  rsa_bits  = 4096
-}
-
 resource "azurerm_resource_group" "rg" {
   name     = "${var.instance_id}-rg"
   location = var.region
   tags     = var.metadata
 }
 
+# Instances that don't join a network.vpc get a private network
+locals {
+  own_network = var.subnet_id == null
+  subnet_id   = local.own_network ? azurerm_subnet.subnet[0].id : var.subnet_id
+}
+
 resource "azurerm_virtual_network" "vnet" {
+  count               = local.own_network ? 1 : 0
   name                = "${var.instance_id}-vnet"
   address_space       = ["10.0.0.0/16"]
   location            = azurerm_resource_group.rg.location
@@ -41,12 +19,24 @@ resource "azurerm_virtual_network" "vnet" {
 }
 
 resource "azurerm_subnet" "subnet" {
+  count                = local.own_network ? 1 : 0
   name                 = "internal"
   resource_group_name  = azurerm_resource_group.rg.name
-  virtual_network_name = azurerm_virtual_network.vnet.name
+  virtual_network_name = azurerm_virtual_network.vnet[0].name
   address_prefixes     = ["10.0.1.0/24"]
 }
 
+# The private network resources became conditional
+moved {
+  from = azurerm_virtual_network.vnet
+  to   = azurerm_virtual_network.vnet[0]
+}
+
+moved {
+  from = azurerm_subnet.subnet
+  to   = azurerm_subnet.subnet[0]
+}
+
 resource "azurerm_public_ip" "pip" {
   name                = "${var.instance_id}-pip"
   resource_group_name = azurerm_resource_group.rg.name
@@ -60,30 +50,23 @@ resource "azurerm_network_security_group" "nsg" {
   location            = azurerm_resource_group.rg.location
   resource_group_name = azurerm_resource_group.rg.name
 
-  security_rule {
-    name                       = "SSH"
-    priority                   = 1001
-    direction                  = "Inbound"
-    access                     = "Allow"
-    protocol                   = "Tcp"
-    source_port_range          = "*"
-    destination_port_range     = "22"
-    source_address_prefix      = "*"
-    destination_address_prefix = "*"
-  }
-
+  # Rules are translated for NSGs by the provisioner; cidrs are the sources
+  # of inbound and the destinations of outbound rules
   dynamic "security_rule" {
-    for_each = var.allowed_ports
+    for_each = var.firewall_rules
     content {
-      name                       = "Allow-${security_rule.value}"
-      priority                   = 1002 + security_rule.key
-      direction                  = "Inbound"
-      access                     = "Allow"
-      protocol                   = "Tcp"
-      source_port_range          = "*"
-      destination_port_range     = security_rule.value
-      source_address_prefix      = "*"
-      destination_address_prefix = "*"
+      name                         = security_rule.value.name
+      description                  = security_rule.value.description
+      priority                     = security_rule.value.priority
+      direction                    = security_rule.value.direction
+      access                       = security_rule.value.access
+      protocol                     = security_rule.value.protocol
+      source_port_range            = "*"
+      destination_port_range       = security_rule.value.port_range
+      source_address_prefix        = security_rule.value.direction == "Inbound" ? null : "*"
+      source_address_prefixes      = security_rule.value.direction == "Inbound" ? security_rule.value.cidrs : null
+      destination_address_prefix   = security_rule.value.direction == "Inbound" ? "*" : null
+      destination_address_prefixes = security_rule.value.direction == "Inbound" ? null : security_rule.value.cidrs
     }
   }
 }
@@ -95,7 +78,7 @@ resource "azurerm_network_interface" "nic" {
 
   ip_configuration {
     name                          = "internal"
-    subnet_id                     = azurerm_subnet.subnet.id
+    subnet_id                     = local.subnet_id
     private_ip_address_allocation = "Dynamic"
     public_ip_address_id          = azurerm_public_ip.pip.id
   }
@@ -110,17 +93,17 @@ resource "azurerm_linux_virtual_machine" "vm" {
   name                = var.instance_id
   resource_group_name = azurerm_resource_group.rg.name
   location            = azurerm_resource_group.rg.location
-  size                = local.size_map[var.size]
+  size                = var.machine_type
   admin_username      = var.admin_username
+  custom_data         = var.user_data == null ? null : base64encode(var.user_data)
 
   network_interface_ids = [
     azurerm_network_interface.nic.id,
   ]
 
   admin_ssh_key {
-    username = var.admin_username
-    # Use provided key OR generated key
-    public_key = var.ssh_public_key != "" ? var.ssh_public_key : tls_private_key.example[0].public_key_openssh
+    username   = var.admin_username
+    public_key = var.ssh_public_key
   }
 
   os_disk {
@@ -130,20 +113,13 @@ resource "azurerm_linux_virtual_machine" "vm" {
   }
 
   source_image_reference {
-    publisher = local.os_map[var.os].publisher
-    offer     = local.os_map[var.os].offer
-    sku       = local.os_map[var.os].sku
-    version   = local.os_map[var.os].version
+    publisher = var.image.publisher
+    offer     = var.image.offer
+    sku       = var.image.sku
+    version   = var.image.version
   }
 
   tags = merge(var.metadata, {
     managed_by = "sky-control"
   })
 }
-
-resource "local_file" "private_key" {
-  count           = var.ssh_public_key == "" ? 1 : 0
-  content         = tls_private_key.example[0].private_key_pem
-  filename        = "${path.module}/${var.instance_id}.pem"
-  file_permission = "0600"
-}
@@ -10,6 +10,14 @@ output "private_ip" {
   value = azurerm_linux_virtual_machine.vm.private_ip_address
 }
 
+output "network_interface_id" {
+  value = azurerm_network_interface.nic.id
+}
+
 output "ssh_connection_string" {
-  value = var.ssh_public_key == "" ? "ssh -i ${var.instance_id}.pem ${var.admin_username}@${azurerm_public_ip.pip.ip_address}" : "ssh ${var.admin_username}@${azurerm_public_ip.pip.ip_address}"
+  value = var.private_key_path != "" ? "ssh -i ${var.private_key_path} ${var.admin_username}@${azurerm_public_ip.pip.ip_address}" : "ssh ${var.admin_username}@${azurerm_public_ip.pip.ip_address}"
+}
+
+output "ssh_user" {
+  value = var.admin_username
 }
@@ -8,25 +8,19 @@ variable "instance_id" {
   type        = string
 }
 
-variable "size" {
-  description = "small, medium, large."
+variable "machine_type" {
+  description = "VM size (resolved from the size in parser/catalog.json)."
   type        = string
-
-  validation {
-    condition     = contains(["small", "medium", "large"], var.size)
-    error_message = "Size must be: small, medium, or large."
-  }
 }
 
-variable "os" {
-  description = "ubuntu, debian."
-  type        = string
-  default     = "ubuntu"
-
-  validation {
-    condition     = contains(["ubuntu", "debian"], var.os)
-    error_message = "OS must be: ubuntu or debian."
-  }
+variable "image" {
+  description = "Marketplace image reference (resolved from the OS in parser/catalog.json or the service's image)."
+  type = object({
+    publisher = string
+    offer     = string
+    sku       = string
+    version   = string
+  })
 }
 
 variable "disk_size_gb" {
@@ -48,13 +42,47 @@ variable "admin_username" {
 }
 
 variable "ssh_public_key" {
-  description = "SSH Public Key string. If empty, one will be generated."
+  description = "SSH Public Key string. Generated per project by the provisioner if not configured."
+  type        = string
+}
+
+variable "private_key_path" {
+  description = "Path of the private key matching ssh_public_key, used in the connection string."
   type        = string
   default     = ""
 }
 
-variable "allowed_ports" {
-  description = "List of ports to allow ingress traffic on."
-  type        = list(number)
-  default     = []
-}
\ No newline at end of file
+variable "firewall_rules" {
+  description = "NSG rules (translated from the service's firewall settings by the provisioner)."
+  type = list(object({
+    name        = string
+    description = string
+    priority    = number
+    direction   = string
+    access      = string
+    protocol    = string
+    port_range  = string
+    cidrs       = list(string)
+  }))
+  default = [{
+    name        = "rule-0"
+    description = "SSH"
+    priority    = 1001
+    direction   = "Inbound"
+    access      = "Allow"
+    protocol    = "Tcp"
+    port_range  = "22"
+    cidrs       = ["0.0.0.0/0"]
+  }]
+}
+variable "user_data" {
+  description = "Cloud-init user data rendered by the provisioner."
+  type        = string
+  default     = null
+}
+
+variable "subnet_id" {
+  description = "Subnet of the network.vpc the instance joins; a private network is created when null."
+  type        = string
+  default     = null
+}
@@ -0,0 +1,29 @@
+resource "azurerm_resource_group" "rg" {
+  name     = "${var.cluster_id}-rg"
+  location = var.region
+  tags     = var.metadata
+}
+
+resource "azurerm_kubernetes_cluster" "cluster" {
+  name                = var.cluster_id
+  location            = azurerm_resource_group.rg.location
+  resource_group_name = azurerm_resource_group.rg.name
+  kubernetes_version  = var.kubernetes_version
+
+  # DNS prefixes allow letters, digits and '-' only
+  dns_prefix = trim(substr(replace(var.cluster_id, "_", "-"), 0, 54), "-")
+
+  default_node_pool {
+    name       = "default"
+    vm_size    = var.machine_type
+    node_count = var.node_count
+  }
+
+  identity {
+    type = "SystemAssigned"
+  }
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
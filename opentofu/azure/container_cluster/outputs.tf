@@ -0,0 +1,18 @@
+output "endpoint" {
+  value = "https://${azurerm_kubernetes_cluster.cluster.fqdn}"
+}
+
+output "kubernetes_version" {
+  value = azurerm_kubernetes_cluster.cluster.current_kubernetes_version
+}
+
+output "node_count" {
+  value = var.node_count
+}
+
+# Written to <cluster_id>/kubeconfig by the provisioner; holds the cluster's
+# admin credentials
+output "kubeconfig" {
+  value     = azurerm_kubernetes_cluster.cluster.kube_config_raw
+  sensitive = true
+}
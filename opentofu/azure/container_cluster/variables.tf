@@ -0,0 +1,32 @@
+variable "region" {
+  description = "Azure Region (e.g., West Europe, East US)."
+  type        = string
+}
+
+variable "cluster_id" {
+  description = "Name of the AKS cluster."
+  type        = string
+}
+
+variable "machine_type" {
+  description = "VM size of the nodes (resolved from the size catalog)."
+  type        = string
+}
+
+variable "node_count" {
+  description = "Number of nodes."
+  type        = number
+  default     = 2
+}
+
+variable "kubernetes_version" {
+  description = "Kubernetes minor version (e.g. 1.31); the AKS default when null."
+  type        = string
+  default     = null
+}
+
+variable "metadata" {
+  description = "Tags applied to the cluster."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,126 @@
+locals {
+  postgres = var.engine == "postgres"
+  port     = local.postgres ? 5432 : 3306
+  scheme   = local.postgres ? "postgresql" : "mysql"
+
+  # PostgreSQL servers only come in fixed storage sizes
+  postgres_storage_mb = [
+    for mb in [32768, 65536, 131072, 262144, 524288, 1048576, 2097152, 4193280, 4194304, 8388608, 16777216] :
+    mb if mb >= var.storage_gb * 1024
+  ][0]
+
+  # Firewall rules take address ranges; 0.0.0.0-0.0.0.0 admits Azure services
+  # only, which keeps private databases off the Internet
+  firewall_rules = var.public_access ? {
+    for i, cidr in var.allowed_cidrs : "allowed-${i}" => { start = cidrhost(cidr, 0), end = cidrhost(cidr, -1) }
+    } : {
+    "azure-services" = { start = "0.0.0.0", end = "0.0.0.0" }
+  }
+
+  host = local.postgres ? azurerm_postgresql_flexible_server.db[0].fqdn : azurerm_mysql_flexible_server.db[0].fqdn
+}
+
+resource "random_password" "password" {
+  length      = 32
+  special     = false # Keeps the connection string free of escaping
+  min_lower   = 1
+  min_upper   = 1
+  min_numeric = 1
+}
+
+resource "azurerm_resource_group" "rg" {
+  name     = "${var.database_id}-rg"
+  location = var.region
+  tags     = var.metadata
+}
+
+resource "azurerm_postgresql_flexible_server" "db" {
+  count = local.postgres ? 1 : 0
+
+  name                = var.database_id
+  resource_group_name = azurerm_resource_group.rg.name
+  location            = azurerm_resource_group.rg.location
+  version             = var.engine_version
+  sku_name            = var.tier
+  storage_mb          = local.postgres_storage_mb
+
+  administrator_login    = var.admin_username
+  administrator_password = random_password.password.result
+  backup_retention_days  = var.backup_retention_days
+
+  public_network_access_enabled = true
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+
+  lifecycle {
+    # Azure picks the availability zone
+    ignore_changes = [zone]
+  }
+}
+
+resource "azurerm_postgresql_flexible_server_database" "db" {
+  count = local.postgres ? 1 : 0
+
+  name      = var.database_name
+  server_id = azurerm_postgresql_flexible_server.db[0].id
+  charset   = "UTF8"
+  collation = "en_US.utf8"
+}
+
+resource "azurerm_postgresql_flexible_server_firewall_rule" "rule" {
+  for_each = local.postgres ? local.firewall_rules : {}
+
+  name             = each.key
+  server_id        = azurerm_postgresql_flexible_server.db[0].id
+  start_ip_address = each.value.start
+  end_ip_address   = each.value.end
+}
+
+resource "azurerm_mysql_flexible_server" "db" {
+  count = local.postgres ? 0 : 1
+
+  name                = var.database_id
+  resource_group_name = azurerm_resource_group.rg.name
+  location            = azurerm_resource_group.rg.location
+  version             = var.engine_version
+  sku_name            = var.tier
+
+  storage {
+    size_gb = var.storage_gb
+  }
+
+  administrator_login    = var.admin_username
+  administrator_password = random_password.password.result
+  backup_retention_days  = var.backup_retention_days
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+
+  lifecycle {
+    # Azure picks the availability zone
+    ignore_changes = [zone]
+  }
+}
+
+resource "azurerm_mysql_flexible_database" "db" {
+  count = local.postgres ? 0 : 1
+
+  name                = var.database_name
+  resource_group_name = azurerm_resource_group.rg.name
+  server_name         = azurerm_mysql_flexible_server.db[0].name
+  charset             = "utf8mb4"
+  collation           = "utf8mb4_unicode_ci"
+}
+
+resource "azurerm_mysql_flexible_server_firewall_rule" "rule" {
+  for_each = local.postgres ? {} : local.firewall_rules
+
+  name                = each.key
+  resource_group_name = azurerm_resource_group.rg.name
+  server_name         = azurerm_mysql_flexible_server.db[0].name
+  start_ip_address    = each.value.start
+  end_ip_address      = each.value.end
+}
@@ -0,0 +1,25 @@
+output "host" {
+  value = local.host
+}
+
+output "port" {
+  value = local.port
+}
+
+output "username" {
+  value = var.admin_username
+}
+
+output "database_name" {
+  value = var.database_name
+}
+
+output "password" {
+  value     = random_password.password.result
+  sensitive = true
+}
+
+output "connection_string" {
+  value     = "${local.scheme}://${var.admin_username}:${random_password.password.result}@${local.host}:${local.port}/${var.database_name}"
+  sensitive = true
+}
@@ -0,0 +1,77 @@
+locals {
+  fqdn = var.record_name == "@" ? var.dns_zone : "${var.record_name}.${var.dns_zone}"
+  tags = { managed_by = "sky-control" }
+}
+
+# The zone is managed outside the provisioner and found in the subscription
+data "azurerm_dns_zone" "zone" {
+  name = var.dns_zone
+}
+
+resource "azurerm_dns_a_record" "record" {
+  count = var.record_type == "A" ? 1 : 0
+
+  name                = var.record_name
+  zone_name           = data.azurerm_dns_zone.zone.name
+  resource_group_name = data.azurerm_dns_zone.zone.resource_group_name
+  ttl                 = var.ttl
+  records             = var.records
+  tags                = local.tags
+}
+
+resource "azurerm_dns_aaaa_record" "record" {
+  count = var.record_type == "AAAA" ? 1 : 0
+
+  name                = var.record_name
+  zone_name           = data.azurerm_dns_zone.zone.name
+  resource_group_name = data.azurerm_dns_zone.zone.resource_group_name
+  ttl                 = var.ttl
+  records             = var.records
+  tags                = local.tags
+}
+
+resource "azurerm_dns_cname_record" "record" {
+  count = var.record_type == "CNAME" ? 1 : 0
+
+  name                = var.record_name
+  zone_name           = data.azurerm_dns_zone.zone.name
+  resource_group_name = data.azurerm_dns_zone.zone.resource_group_name
+  ttl                 = var.ttl
+  record              = var.records[0]
+  tags                = local.tags
+}
+
+resource "azurerm_dns_mx_record" "record" {
+  count = var.record_type == "MX" ? 1 : 0
+
+  name                = var.record_name
+  zone_name           = data.azurerm_dns_zone.zone.name
+  resource_group_name = data.azurerm_dns_zone.zone.resource_group_name
+  ttl                 = var.ttl
+  tags                = local.tags
+
+  dynamic "record" {
+    for_each = var.records
+    content {
+      preference = split(" ", record.value)[0]
+      exchange   = split(" ", record.value)[1]
+    }
+  }
+}
+
+resource "azurerm_dns_txt_record" "record" {
+  count = var.record_type == "TXT" ? 1 : 0
+
+  name                = var.record_name
+  zone_name           = data.azurerm_dns_zone.zone.name
+  resource_group_name = data.azurerm_dns_zone.zone.resource_group_name
+  ttl                 = var.ttl
+  tags                = local.tags
+
+  dynamic "record" {
+    for_each = var.records
+    content {
+      value = record.value
+    }
+  }
+}
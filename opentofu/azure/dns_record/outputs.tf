@@ -0,0 +1,11 @@
+output "fqdn" {
+  value = local.fqdn
+}
+
+output "record_type" {
+  value = var.record_type
+}
+
+output "records" {
+  value = var.records
+}
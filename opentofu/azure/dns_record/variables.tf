@@ -0,0 +1,30 @@
+variable "dns_zone" {
+  description = "Existing DNS zone the record is created in (e.g. example.com)."
+  type        = string
+}
+
+variable "record_name" {
+  description = "Record name relative to the zone; @ for the zone apex."
+  type        = string
+}
+
+variable "record_type" {
+  description = "Record type: A, AAAA, CNAME, MX or TXT."
+  type        = string
+
+  validation {
+    condition     = contains(["A", "AAAA", "CNAME", "MX", "TXT"], var.record_type)
+    error_message = "Record type must be: A, AAAA, CNAME, MX or TXT."
+  }
+}
+
+variable "ttl" {
+  description = "TTL in seconds."
+  type        = number
+  default     = 300
+}
+
+variable "records" {
+  description = "Record values; MX values as \"<priority> <host>\"."
+  type        = list(string)
+}
@@ -0,0 +1,66 @@
+resource "azurerm_resource_group" "rg" {
+  name     = "${var.load_balancer_id}-rg"
+  location = var.region
+  tags     = var.metadata
+}
+
+resource "azurerm_public_ip" "lb" {
+  name                = "${var.load_balancer_id}-pip"
+  resource_group_name = azurerm_resource_group.rg.name
+  location            = azurerm_resource_group.rg.location
+  allocation_method   = "Static"
+  sku                 = "Standard"
+}
+
+resource "azurerm_lb" "lb" {
+  name                = var.load_balancer_id
+  resource_group_name = azurerm_resource_group.rg.name
+  location            = azurerm_resource_group.rg.location
+  sku                 = "Standard"
+
+  frontend_ip_configuration {
+    name                 = "frontend"
+    public_ip_address_id = azurerm_public_ip.lb.id
+  }
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
+
+resource "azurerm_lb_backend_address_pool" "backends" {
+  name            = "backends"
+  loadbalancer_id = azurerm_lb.lb.id
+}
+
+# Compute instances name the IP configuration of their NIC "internal"
+resource "azurerm_network_interface_backend_address_pool_association" "backends" {
+  count                   = length(var.network_interface_ids)
+  network_interface_id    = var.network_interface_ids[count.index]
+  ip_configuration_name   = "internal"
+  backend_address_pool_id = azurerm_lb_backend_address_pool.backends.id
+}
+
+resource "azurerm_lb_probe" "health" {
+  name                = "health"
+  loadbalancer_id     = azurerm_lb.lb.id
+  protocol            = var.health_check.protocol == "http" ? "Http" : "Tcp"
+  port                = var.health_check.port
+  request_path        = var.health_check.protocol == "http" ? var.health_check.path : null
+  interval_in_seconds = var.health_check.interval_seconds
+}
+
+# Azure load balancers forward TCP; http listeners are forwarded as is
+resource "azurerm_lb_rule" "listener" {
+  for_each = { for l in var.listeners : tostring(l.port) => l }
+
+  name                           = "port-${each.key}"
+  loadbalancer_id                = azurerm_lb.lb.id
+  protocol                       = "Tcp"
+  frontend_port                  = each.value.port
+  backend_port                   = each.value.target_port
+  frontend_ip_configuration_name = "frontend"
+  backend_address_pool_ids       = [azurerm_lb_backend_address_pool.backends.id]
+  probe_id                       = azurerm_lb_probe.health.id
+  disable_outbound_snat          = true # The VMs keep their own public IPs
+}
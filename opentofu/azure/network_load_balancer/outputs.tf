@@ -0,0 +1,8 @@
+# Public IP address of the load balancer; point an A record at it
+output "endpoint" {
+  value = azurerm_public_ip.lb.ip_address
+}
+
+output "load_balancer_id" {
+  value = azurerm_lb.lb.id
+}
@@ -0,0 +1,39 @@
+variable "region" {
+  description = "Azure Region (e.g., West Europe, East US)."
+  type        = string
+}
+
+variable "load_balancer_id" {
+  description = "Name of the load balancer."
+  type        = string
+}
+
+variable "listeners" {
+  description = "Ports the load balancer accepts traffic on and the ports of the backends they forward to."
+  type = list(object({
+    port        = number
+    protocol    = string
+    target_port = number
+  }))
+}
+
+variable "health_check" {
+  description = "Health check of the backends (normalized by the provisioner)."
+  type = object({
+    protocol         = string
+    path             = string
+    port             = number
+    interval_seconds = number
+  })
+}
+
+variable "network_interface_ids" {
+  description = "Network interfaces of the VMs behind the load balancer (set by the provisioner)."
+  type        = list(string)
+}
+
+variable "metadata" {
+  description = "Tags applied to the load balancer."
+  type        = map(string)
+  default     = {}
+}
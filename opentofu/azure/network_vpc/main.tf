@@ -0,0 +1,26 @@
+resource "azurerm_resource_group" "rg" {
+  name     = "${var.network_id}-rg"
+  location = var.region
+  tags     = var.metadata
+}
+
+resource "azurerm_virtual_network" "vnet" {
+  name                = var.network_id
+  address_space       = [var.cidr]
+  location            = azurerm_resource_group.rg.location
+  resource_group_name = azurerm_resource_group.rg.name
+
+  tags = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
+
+# Subnet ranges are planned by the provisioner
+resource "azurerm_subnet" "subnet" {
+  for_each = { for s in var.subnets : s.name => s }
+
+  name                 = each.key
+  resource_group_name  = azurerm_resource_group.rg.name
+  virtual_network_name = azurerm_virtual_network.vnet.name
+  address_prefixes     = [each.value.cidr]
+}
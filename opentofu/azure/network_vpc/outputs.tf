@@ -0,0 +1,11 @@
+output "vnet_id" {
+  value = azurerm_virtual_network.vnet.id
+}
+
+output "resource_group_name" {
+  value = azurerm_resource_group.rg.name
+}
+
+output "subnet_ids" {
+  value = { for name, subnet in azurerm_subnet.subnet : name => subnet.id }
+}
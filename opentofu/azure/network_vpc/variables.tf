@@ -0,0 +1,28 @@
+variable "region" {
+  description = "Azure Region (e.g., West Europe, East US)."
+  type        = string
+}
+
+variable "network_id" {
+  description = "Name of the virtual network."
+  type        = string
+}
+
+variable "cidr" {
+  description = "Address space of the virtual network (e.g. 10.1.0.0/16)."
+  type        = string
+}
+
+variable "subnets" {
+  description = "Subnets with their ranges (planned by the provisioner)."
+  type = list(object({
+    name = string
+    cidr = string
+  }))
+}
+
+variable "metadata" {
+  description = "Tags applied to the resource group and virtual network."
+  type        = map(string)
+  default     = {}
+}
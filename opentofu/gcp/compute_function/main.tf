@@ -0,0 +1,57 @@
+# Cloud Functions builds the code from a Cloud Storage object; the bucket
+# name must be globally unique
+resource "random_id" "bucket" {
+  byte_length = 8
+}
+
+resource "google_storage_bucket" "source" {
+  name                        = "gcf-source-${random_id.bucket.hex}"
+  location                    = var.region
+  uniform_bucket_level_access = true
+  force_destroy               = true
+  labels                      = var.metadata
+}
+
+# The archive name carries its digest, so changed code is a new object
+resource "google_storage_bucket_object" "source" {
+  name   = "${var.function_id}/${basename(var.source_zip)}"
+  bucket = google_storage_bucket.source.name
+  source = var.source_zip
+}
+
+resource "google_cloudfunctions2_function" "function" {
+  name     = var.function_id
+  location = var.region
+
+  build_config {
+    runtime     = var.runtime
+    entry_point = var.entry_point
+
+    source {
+      storage_source {
+        bucket = google_storage_bucket.source.name
+        object = google_storage_bucket_object.source.name
+      }
+    }
+  }
+
+  service_config {
+    available_memory      = "${var.memory_mb}M"
+    timeout_seconds       = var.timeout_seconds
+    environment_variables = var.environment
+    ingress_settings      = "ALLOW_ALL"
+  }
+
+  labels = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
+
+# Functions run as Cloud Run services; allUsers may invoke them so the URL
+# is public like on AWS and Azure
+resource "google_cloud_run_service_iam_member" "public" {
+  location = google_cloudfunctions2_function.function.location
+  service  = google_cloudfunctions2_function.function.service_config[0].service
+  role     = "roles/run.invoker"
+  member   = "allUsers"
+}
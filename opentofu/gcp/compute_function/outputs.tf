@@ -0,0 +1,7 @@
+output "url" {
+  value = trimsuffix(google_cloudfunctions2_function.function.service_config[0].uri, "/")
+}
+
+output "function_name" {
+  value = google_cloudfunctions2_function.function.name
+}
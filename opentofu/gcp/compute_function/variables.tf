@@ -0,0 +1,53 @@
+variable "project_id" {
+  description = "The GCP Project ID."
+  type        = string
+}
+
+variable "region" {
+  description = "GCP Region (e.g. europe-west1)."
+  type        = string
+}
+
+variable "function_id" {
+  description = "Name of the Cloud Function."
+  type        = string
+}
+
+variable "runtime" {
+  description = "Cloud Functions runtime (resolved from parser/catalog.json by the provisioner)."
+  type        = string
+}
+
+variable "entry_point" {
+  description = "Name of the function called on requests (e.g. handler)."
+  type        = string
+}
+
+variable "source_zip" {
+  description = "Path of the zip archive with the function code, packaged by the provisioner."
+  type        = string
+}
+
+variable "memory_mb" {
+  description = "Memory in MB."
+  type        = number
+  default     = 256
+}
+
+variable "timeout_seconds" {
+  description = "Request timeout in seconds."
+  type        = number
+  default     = 60
+}
+
+variable "environment" {
+  description = "Environment variables of the function."
+  type        = map(string)
+  default     = {}
+}
+
+variable "metadata" {
+  description = "Labels applied to the function."
+  type        = map(string)
+  default     = {}
+}
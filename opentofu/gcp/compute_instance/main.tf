@@ -1,20 +1,7 @@
-locals {
-  size_map = {
-    "small"  = "e2-micro"
-    "medium" = "e2-medium"
-    "large"  = "e2-standard-2"
-  }
-
-  os_map = {
-    "debian" = "debian-cloud/debian-11"
-    "ubuntu" = "ubuntu-os-cloud/ubuntu-2204-lts"
-  }
-}
-
 resource "google_compute_instance" "vm" {
   name = var.instance_id
 
-  machine_type = local.size_map[var.size]
+  machine_type = var.machine_type
 
   zone    = var.zone
   project = var.project_id
@@ -23,19 +10,27 @@ resource "google_compute_instance" "vm" {
 
   boot_disk {
     initialize_params {
-      image  = local.os_map[var.os]
+      image  = var.image
       size   = var.disk_size_gb
       labels = var.metadata
     }
   }
 
+  # Instances that don't join a network.vpc use the default network
   network_interface {
-    network = "default"
+    network    = var.subnetwork == null ? "default" : null
+    subnetwork = var.subnetwork
     access_config {
     }
   }
 
-  tags = ["sky-control-firewall"]
+  # Scripts run as startup-script since not every image ships cloud-init
+  metadata = merge(
+    { ssh-keys = "${var.admin_username}:${trimspace(var.ssh_public_key)}" },
+    var.user_data == null ? {} : startswith(var.user_data, "#!") ? { startup-script = var.user_data } : { user-data = var.user_data },
+  )
+
+  tags = [var.instance_id]
 
   labels = merge(var.metadata, {
     managed_by = "sky-control"
@@ -46,16 +41,34 @@ resource "google_compute_instance" "vm" {
   }
 }
 
+# Rules are translated by the provisioner and target this instance's tag only
 resource "google_compute_firewall" "fw" {
-  name    = "${var.instance_id}-fw"
-  network = "default"
-  project = var.project_id
+  for_each = { for i, r in var.firewall_rules : "${var.instance_id}-fw${i}" => r }
+
+  name        = each.key
+  description = each.value.description
+  network     = var.network == null ? "default" : var.network
+  project     = var.project_id
+  direction   = each.value.direction
+  priority    = each.value.priority
+
+  dynamic "allow" {
+    for_each = each.value.action == "allow" ? [each.value] : []
+    content {
+      protocol = allow.value.protocol
+      ports    = length(allow.value.ports) > 0 ? allow.value.ports : null
+    }
+  }
 
-  allow {
-    protocol = "tcp"
-    ports    = concat(["22"], [for p in var.allowed_ports : tostring(p)])
+  dynamic "deny" {
+    for_each = each.value.action == "deny" ? [each.value] : []
+    content {
+      protocol = deny.value.protocol
+      ports    = length(deny.value.ports) > 0 ? deny.value.ports : null
+    }
   }
 
-  source_ranges = ["0.0.0.0/0"]
-  target_tags   = ["sky-control-firewall"]
+  source_ranges      = each.value.direction == "INGRESS" ? each.value.ranges : null
+  destination_ranges = each.value.direction == "EGRESS" ? each.value.ranges : null
+  target_tags        = [var.instance_id]
 }
@@ -9,3 +9,11 @@ output "internal_ip" {
 output "external_ip" {
   value = length(google_compute_instance.vm.network_interface.0.access_config) > 0 ? google_compute_instance.vm.network_interface.0.access_config.0.nat_ip : "None"
 }
+
+output "ssh_user" {
+  value = var.admin_username
+}
+
+output "ssh_connection_string" {
+  value = var.private_key_path != "" ? "ssh -i ${var.private_key_path} ${var.admin_username}@${google_compute_instance.vm.network_interface.0.access_config.0.nat_ip}" : "ssh ${var.admin_username}@${google_compute_instance.vm.network_interface.0.access_config.0.nat_ip}"
+}
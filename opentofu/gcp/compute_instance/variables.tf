@@ -13,20 +13,14 @@ variable "instance_id" {
   type        = string
 }
 
-variable "size" {
-  description = "small, medium, large."
+variable "machine_type" {
+  description = "Machine type (resolved from the size in parser/catalog.json)."
   type        = string
-
-  validation {
-    condition     = contains(["small", "medium", "large"], var.size)
-    error_message = "Size must be: small, medium, or large."
-  }
 }
 
-variable "os" {
-  description = "OS Family: debian, ubuntu"
+variable "image" {
+  description = "Boot image (resolved from the OS in parser/catalog.json or the service's image)."
   type        = string
-  default     = "debian"
 }
 
 variable "disk_size_gb" {
@@ -41,8 +35,59 @@ variable "metadata" {
   default     = {}
 }
 
-variable "allowed_ports" {
-  description = "List of ports to allow ingress traffic on."
-  type        = list(number)
-  default     = []
+variable "firewall_rules" {
+  description = "Firewall rules (translated from the service's firewall settings by the provisioner)."
+  type = list(object({
+    description = string
+    direction   = string
+    action      = string
+    priority    = number
+    protocol    = string
+    ports       = list(string)
+    ranges      = list(string)
+  }))
+  default = [{
+    description = "SSH"
+    direction   = "INGRESS"
+    action      = "allow"
+    priority    = 1000
+    protocol    = "tcp"
+    ports       = ["22"]
+    ranges      = ["0.0.0.0/0"]
+  }]
+}
+
+variable "admin_username" {
+  description = "Username the SSH key is installed for."
+  type        = string
+  default     = "gcpuser"
+}
+
+variable "ssh_public_key" {
+  description = "SSH Public Key string. Generated per project by the provisioner if not configured."
+  type        = string
+}
+
+variable "private_key_path" {
+  description = "Path of the private key matching ssh_public_key, used in the connection string."
+  type        = string
+  default     = ""
+}
+
+variable "user_data" {
+  description = "Cloud-init user data rendered by the provisioner."
+  type        = string
+  default     = null
+}
+
+variable "network" {
+  description = "VPC network of the network.vpc the instance joins; the default network is used when null."
+  type        = string
+  default     = null
+}
+
+variable "subnetwork" {
+  description = "Subnetwork of the network.vpc the instance joins."
+  type        = string
+  default     = null
 }
@@ -0,0 +1,59 @@
+# A zonal cluster in the default network; the default node pool is replaced
+# by one that can be resized without recreating the cluster
+resource "google_container_cluster" "cluster" {
+  name                     = var.cluster_id
+  project                  = var.project_id
+  location                 = var.zone
+  min_master_version       = var.kubernetes_version
+  remove_default_node_pool = true
+  initial_node_count       = 1
+  deletion_protection      = false
+
+  resource_labels = merge(var.metadata, {
+    managed_by = "sky-control"
+  })
+}
+
+resource "google_container_node_pool" "nodes" {
+  name       = "default"
+  project    = var.project_id
+  location   = var.zone
+  cluster    = google_container_cluster.cluster.name
+  node_count = var.node_count
+
+  node_config {
+    machine_type = var.machine_type
+    labels       = var.metadata
+    oauth_scopes = ["https://www.googleapis.com/auth/cloud-platform"]
+  }
+}
+
+# kubectl authenticates through the gke-gcloud-auth-plugin of the gcloud CLI
+locals {
+  kubeconfig = yamlencode({
+    apiVersion = "v1"
+    kind       = "Config"
+    clusters = [{
+      name = var.cluster_id
+      cluster = {
+        server                       = "https://${google_container_cluster.cluster.endpoint}"
+        "certificate-authority-data" = google_container_cluster.cluster.master_auth[0].cluster_ca_certificate
+      }
+    }]
+    users = [{
+      name = var.cluster_id
+      user = {
+        exec = {
+          apiVersion         = "client.authentication.k8s.io/v1beta1"
+          command            = "gke-gcloud-auth-plugin"
+          provideClusterInfo = true
+        }
+      }
+    }]
+    contexts = [{
+      name    = var.cluster_id
+      context = { cluster = var.cluster_id, user = var.cluster_id }
+    }]
+    "current-context" = var.cluster_id
+  })
+}
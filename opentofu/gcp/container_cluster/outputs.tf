@@ -0,0 +1,17 @@
+output "endpoint" {
+  value = "https://${google_container_cluster.cluster.endpoint}"
+}
+
+output "kubernetes_version" {
+  value = google_container_cluster.cluster.master_version
+}
+
+output "node_count" {
+  value = google_container_node_pool.nodes.node_count
+}
+
+# Written to <cluster_id>/kubeconfig by the provisioner
+output "kubeconfig" {
+  value     = local.kubeconfig
+  sensitive = true
+}
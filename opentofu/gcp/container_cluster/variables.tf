@@ -0,0 +1,37 @@
+variable "project_id" {
+  description = "The GCP Project ID."
+  type        = string
+}
+
+variable "zone" {
+  description = "Zone of the cluster (e.g. europe-west1-b)."
+  type        = string
+}
+
+variable "cluster_id" {
+  description = "Name of the GKE cluster."
+  type        = string
+}
+
+variable "machine_type" {
+  description = "Machine type of the nodes (resolved from the size catalog)."
+  type        = string
+}
+
+variable "node_count" {
+  description = "Number of nodes."
+  type        = number
+  default     = 2
+}
+
+variable "kubernetes_version" {
+  description = "Kubernetes minor version (e.g. 1.31); the GKE default when null."
+  type        = string
+  default     = null
+}
+
+variable "metadata" {
+  description = "Labels applied to the cluster and its nodes."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,66 @@
+locals {
+  port   = var.engine == "postgres" ? 5432 : 3306
+  scheme = var.engine == "postgres" ? "postgresql" : "mysql"
+}
+
+resource "random_password" "password" {
+  length      = 32
+  special     = false # Keeps the connection string free of escaping
+  min_lower   = 1
+  min_upper   = 1
+  min_numeric = 1
+}
+
+resource "google_sql_database_instance" "db" {
+  name             = var.database_id
+  project          = var.project_id
+  region           = var.region
+  database_version = var.engine_version
+
+  deletion_protection = false
+
+  settings {
+    # The catalog tiers are Enterprise edition machine types
+    edition   = "ENTERPRISE"
+    tier      = var.tier
+    disk_size = var.storage_gb
+    disk_type = "PD_SSD"
+
+    backup_configuration {
+      enabled = var.backup_retention_days > 0
+      backup_retention_settings {
+        retained_backups = max(var.backup_retention_days, 1) # Backups are daily
+      }
+    }
+
+    # Without authorized networks, the public IP only accepts the Cloud SQL
+    # Auth Proxy and connectors
+    ip_configuration {
+      ipv4_enabled = true
+      dynamic "authorized_networks" {
+        for_each = var.public_access ? var.allowed_cidrs : []
+        content {
+          name  = "allowed-${authorized_networks.key}"
+          value = authorized_networks.value
+        }
+      }
+    }
+
+    user_labels = merge(var.metadata, {
+      managed_by = "sky-control"
+    })
+  }
+}
+
+resource "google_sql_database" "db" {
+  name     = var.database_name
+  project  = var.project_id
+  instance = google_sql_database_instance.db.name
+}
+
+resource "google_sql_user" "admin" {
+  name     = var.admin_username
+  project  = var.project_id
+  instance = google_sql_database_instance.db.name
+  password = random_password.password.result
+}
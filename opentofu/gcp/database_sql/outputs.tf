@@ -0,0 +1,30 @@
+output "host" {
+  value = google_sql_database_instance.db.public_ip_address
+}
+
+output "port" {
+  value = local.port
+}
+
+output "username" {
+  value = google_sql_user.admin.name
+}
+
+output "database_name" {
+  value = google_sql_database.db.name
+}
+
+output "connection_name" {
+  description = "Instance connection name for the Cloud SQL Auth Proxy."
+  value       = google_sql_database_instance.db.connection_name
+}
+
+output "password" {
+  value     = random_password.password.result
+  sensitive = true
+}
+
+output "connection_string" {
+  value     = "${local.scheme}://${google_sql_user.admin.name}:${random_password.password.result}@${google_sql_database_instance.db.public_ip_address}:${local.port}/${google_sql_database.db.name}"
+  sensitive = true
+}
@@ -0,0 +1,76 @@
+variable "project_id" {
+  description = "The GCP Project ID."
+  type        = string
+}
+
+variable "region" {
+  description = "Region of the database instance (e.g. europe-west1)."
+  type        = string
+}
+
+variable "database_id" {
+  description = "Name of the database server."
+  type        = string
+}
+
+variable "engine" {
+  description = "Database engine: postgres or mysql."
+  type        = string
+
+  validation {
+    condition     = contains(["postgres", "mysql"], var.engine)
+    error_message = "Engine must be: postgres or mysql."
+  }
+}
+
+variable "engine_version" {
+  description = "Provider engine version (resolved from parser/catalog.json by the provisioner)."
+  type        = string
+}
+
+variable "tier" {
+  description = "Provider instance class (resolved from parser/catalog.json by the provisioner)."
+  type        = string
+}
+
+variable "storage_gb" {
+  description = "Storage in GB."
+  type        = number
+  default     = 20
+}
+
+variable "backup_retention_days" {
+  description = "Days automated backups are kept; 0 disables backups."
+  type        = number
+  default     = 7
+}
+
+variable "public_access" {
+  description = "Give the database a public endpoint reachable from allowed_cidrs."
+  type        = bool
+  default     = false
+}
+
+variable "allowed_cidrs" {
+  description = "IPv4 CIDRs allowed to connect when public_access is set."
+  type        = list(string)
+  default     = []
+}
+
+variable "database_name" {
+  description = "Name of the database created on the server."
+  type        = string
+  default     = "app"
+}
+
+variable "admin_username" {
+  description = "Admin user of the server; its password is generated."
+  type        = string
+  default     = "dbadmin"
+}
+
+variable "metadata" {
+  description = "Arbitrary metadata/labels."
+  type        = map(string)
+  default     = {}
+}
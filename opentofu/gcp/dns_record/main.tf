@@ -0,0 +1,29 @@
+locals {
+  fqdn = var.record_name == "@" ? var.dns_zone : "${var.record_name}.${var.dns_zone}"
+
+  # Cloud DNS wants fully qualified targets and quoted TXT strings
+  rrdatas = [
+    for r in var.records :
+    contains(["CNAME", "MX"], var.record_type) && !endswith(r, ".") ? "${r}." :
+    var.record_type == "TXT" ? "\"${r}\"" : r
+  ]
+}
+
+# Managed zones are looked up by DNS name; the zone is managed outside the
+# provisioner
+data "google_dns_managed_zones" "zones" {
+  project = var.project_id
+}
+
+locals {
+  zone = one([for z in data.google_dns_managed_zones.zones.managed_zones : z.name if z.dns_name == "${var.dns_zone}." && z.visibility == "public"])
+}
+
+resource "google_dns_record_set" "record" {
+  project      = var.project_id
+  managed_zone = local.zone
+  name         = "${local.fqdn}."
+  type         = var.record_type
+  ttl          = var.ttl
+  rrdatas      = local.rrdatas
+}
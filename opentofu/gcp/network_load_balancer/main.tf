@@ -0,0 +1,217 @@
+locals {
+  # http and https listeners get a global external Application Load
+  # Balancer, tcp listeners a regional passthrough Network Load Balancer
+  application  = anytrue([for l in var.listeners : l.protocol != "tcp"])
+  target_ports = distinct([for l in var.listeners : l.target_port])
+  network      = var.network == null ? "default" : var.network
+
+  # Instances are grouped by zone, taken from their self links
+  instance_zones = [for link in var.instances : regex("/zones/([^/]+)/", link)[0]]
+  zones          = distinct(local.instance_zones)
+  instance_names = [for link in var.instances : reverse(split("/", link))[0]]
+
+  # Ranges of Google's health checks (and the proxies of application load
+  # balancers)
+  health_check_ranges = local.application ? ["35.191.0.0/16", "130.211.0.0/22"] : ["35.191.0.0/16", "209.85.152.0/22", "209.85.204.0/22"]
+
+  labels = merge(var.metadata, { managed_by = "sky-control" })
+}
+
+resource "google_compute_instance_group" "backends" {
+  for_each = toset(local.zones)
+
+  name      = "${var.load_balancer_id}-ig${index(local.zones, each.key)}"
+  project   = var.project_id
+  zone      = each.key
+  instances = [for i, link in var.instances : link if local.instance_zones[i] == each.key]
+
+  dynamic "named_port" {
+    for_each = local.target_ports
+    content {
+      name = "port-${named_port.value}"
+      port = named_port.value
+    }
+  }
+}
+
+# Compute instances are tagged with their instance_id
+resource "google_compute_firewall" "health_checks" {
+  name          = "${var.load_balancer_id}-hc"
+  project       = var.project_id
+  network       = local.network
+  direction     = "INGRESS"
+  source_ranges = local.health_check_ranges
+  target_tags   = local.instance_names
+
+  allow {
+    protocol = "tcp"
+    ports    = distinct([for p in concat([var.health_check.port], local.application ? local.target_ports : []) : tostring(p)])
+  }
+}
+
+# Application load balancer: one backend service per backend port and a
+# forwarding rule, URL map and proxy per listener, sharing one address
+
+resource "google_compute_health_check" "hc" {
+  count   = local.application ? 1 : 0
+  name    = "${var.load_balancer_id}-hc"
+  project = var.project_id
+
+  check_interval_sec = var.health_check.interval_seconds
+  timeout_sec        = min(5, var.health_check.interval_seconds)
+
+  dynamic "http_health_check" {
+    for_each = var.health_check.protocol == "http" ? [var.health_check] : []
+    content {
+      port         = http_health_check.value.port
+      request_path = http_health_check.value.path
+    }
+  }
+
+  dynamic "tcp_health_check" {
+    for_each = var.health_check.protocol == "tcp" ? [var.health_check] : []
+    content {
+      port = tcp_health_check.value.port
+    }
+  }
+}
+
+resource "google_compute_backend_service" "backend" {
+  for_each = local.application ? toset([for p in local.target_ports : tostring(p)]) : toset([])
+
+  name                  = "${var.load_balancer_id}-${each.key}"
+  project               = var.project_id
+  protocol              = "HTTP"
+  port_name             = "port-${each.key}"
+  load_balancing_scheme = "EXTERNAL_MANAGED"
+  health_checks         = [google_compute_health_check.hc[0].id]
+
+  dynamic "backend" {
+    for_each = google_compute_instance_group.backends
+    content {
+      group           = backend.value.id
+      balancing_mode  = "UTILIZATION"
+      capacity_scaler = 1.0
+    }
+  }
+}
+
+resource "google_compute_url_map" "listener" {
+  for_each = local.application ? { for l in var.listeners : tostring(l.port) => l } : {}
+
+  name            = "${var.load_balancer_id}-${each.key}"
+  project         = var.project_id
+  default_service = google_compute_backend_service.backend[tostring(each.value.target_port)].id
+}
+
+resource "google_compute_target_http_proxy" "listener" {
+  for_each = { for l in var.listeners : tostring(l.port) => l if l.protocol == "http" }
+
+  name    = "${var.load_balancer_id}-${each.key}"
+  project = var.project_id
+  url_map = google_compute_url_map.listener[each.key].id
+}
+
+resource "google_compute_managed_ssl_certificate" "cert" {
+  count   = length(var.tls_domains) > 0 ? 1 : 0
+  name    = "${var.load_balancer_id}-cert"
+  project = var.project_id
+
+  managed {
+    domains = var.tls_domains
+  }
+}
+
+resource "google_compute_target_https_proxy" "listener" {
+  for_each = { for l in var.listeners : tostring(l.port) => l if l.protocol == "https" }
+
+  name             = "${var.load_balancer_id}-${each.key}"
+  project          = var.project_id
+  url_map          = google_compute_url_map.listener[each.key].id
+  ssl_certificates = [google_compute_managed_ssl_certificate.cert[0].id]
+}
+
+resource "google_compute_global_address" "lb" {
+  count   = local.application ? 1 : 0
+  name    = var.load_balancer_id
+  project = var.project_id
+}
+
+resource "google_compute_global_forwarding_rule" "listener" {
+  for_each = local.application ? { for l in var.listeners : tostring(l.port) => l } : {}
+
+  name                  = "${var.load_balancer_id}-${each.key}"
+  project               = var.project_id
+  ip_address            = google_compute_global_address.lb[0].address
+  ip_protocol           = "TCP"
+  port_range            = each.key
+  load_balancing_scheme = "EXTERNAL_MANAGED"
+  target                = each.value.protocol == "https" ? google_compute_target_https_proxy.listener[each.key].id : google_compute_target_http_proxy.listener[each.key].id
+  labels                = local.labels
+}
+
+# Passthrough load balancer: the backends receive the client connections on
+# the listener ports
+
+resource "google_compute_region_health_check" "hc" {
+  count   = local.application ? 0 : 1
+  name    = "${var.load_balancer_id}-hc"
+  project = var.project_id
+  region  = var.region
+
+  check_interval_sec = var.health_check.interval_seconds
+  timeout_sec        = min(5, var.health_check.interval_seconds)
+
+  dynamic "http_health_check" {
+    for_each = var.health_check.protocol == "http" ? [var.health_check] : []
+    content {
+      port         = http_health_check.value.port
+      request_path = http_health_check.value.path
+    }
+  }
+
+  dynamic "tcp_health_check" {
+    for_each = var.health_check.protocol == "tcp" ? [var.health_check] : []
+    content {
+      port = tcp_health_check.value.port
+    }
+  }
+}
+
+resource "google_compute_region_backend_service" "backend" {
+  count                 = local.application ? 0 : 1
+  name                  = var.load_balancer_id
+  project               = var.project_id
+  region                = var.region
+  protocol              = "TCP"
+  load_balancing_scheme = "EXTERNAL"
+  health_checks         = [google_compute_region_health_check.hc[0].id]
+
+  dynamic "backend" {
+    for_each = google_compute_instance_group.backends
+    content {
+      group          = backend.value.id
+      balancing_mode = "CONNECTION"
+    }
+  }
+}
+
+resource "google_compute_address" "lb" {
+  count   = local.application ? 0 : 1
+  name    = var.load_balancer_id
+  project = var.project_id
+  region  = var.region
+}
+
+resource "google_compute_forwarding_rule" "lb" {
+  count                 = local.application ? 0 : 1
+  name                  = var.load_balancer_id
+  project               = var.project_id
+  region                = var.region
+  ip_address            = google_compute_address.lb[0].address
+  ip_protocol           = "TCP"
+  ports                 = [for l in var.listeners : tostring(l.port)]
+  load_balancing_scheme = "EXTERNAL"
+  backend_service       = google_compute_region_backend_service.backend[0].id
+  labels                = local.labels
+}
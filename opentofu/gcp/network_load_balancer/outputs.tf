@@ -0,0 +1,4 @@
+# Public IP address of the load balancer; point an A record at it
+output "endpoint" {
+  value = local.application ? google_compute_global_address.lb[0].address : google_compute_address.lb[0].address
+}
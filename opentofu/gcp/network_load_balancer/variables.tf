@@ -0,0 +1,56 @@
+variable "project_id" {
+  description = "The GCP Project ID."
+  type        = string
+}
+
+variable "region" {
+  description = "Region of the backends (e.g. europe-west1)."
+  type        = string
+}
+
+variable "load_balancer_id" {
+  description = "Name of the load balancer."
+  type        = string
+}
+
+variable "listeners" {
+  description = "Ports the load balancer accepts traffic on and the ports of the backends they forward to."
+  type = list(object({
+    port        = number
+    protocol    = string
+    target_port = number
+  }))
+}
+
+variable "health_check" {
+  description = "Health check of the backends (normalized by the provisioner)."
+  type = object({
+    protocol         = string
+    path             = string
+    port             = number
+    interval_seconds = number
+  })
+}
+
+variable "tls_domains" {
+  description = "Domains of the Google-managed certificate of https listeners."
+  type        = list(string)
+  default     = []
+}
+
+variable "instances" {
+  description = "Self links of the instances behind the load balancer (set by the provisioner)."
+  type        = list(string)
+}
+
+variable "network" {
+  description = "Network of the backends (set by the provisioner; default network when null)."
+  type        = string
+  default     = null
+}
+
+variable "metadata" {
+  description = "Labels applied to the forwarding rules."
+  type        = map(string)
+  default     = {}
+}
@@ -0,0 +1,13 @@
+terraform {
+  required_providers {
+    google = {
+      source  = "hashicorp/google"
+      version = "~> 7.0"
+    }
+  }
+  required_version = ">= 1.6.0"
+}
+
+provider "google" {
+  project = var.project_id
+}
@@ -0,0 +1,16 @@
+resource "google_compute_network" "vpc" {
+  name                    = var.network_id
+  project                 = var.project_id
+  auto_create_subnetworks = false
+}
+
+# Subnet ranges are planned by the provisioner
+resource "google_compute_subnetwork" "subnet" {
+  for_each = { for s in var.subnets : s.name => s }
+
+  name          = "${var.network_id}-${each.key}"
+  project       = var.project_id
+  region        = var.region
+  network       = google_compute_network.vpc.id
+  ip_cidr_range = each.value.cidr
+}
@@ -0,0 +1,22 @@
+variable "project_id" {
+  description = "The GCP Project ID."
+  type        = string
+}
+
+variable "region" {
+  description = "Region of the subnetworks (e.g. europe-west1)."
+  type        = string
+}
+
+variable "network_id" {
+  description = "Name of the VPC network."
+  type        = string
+}
+
+variable "subnets" {
+  description = "Subnetworks with their ranges (planned by the provisioner)."
+  type = list(object({
+    name = string
+    cidr = string
+  }))
+}
@@ -0,0 +1,201 @@
+// Package bundle packages the source directories of compute.function services
+// into zip archives. The same files always produce the same archive, whatever
+// their timestamps, ownership or the order the file system lists them in, so
+// unchanged code is never uploaded again.
+package bundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// excludedDirs and excludedFiles are left out of every archive: version
+// control data and caches that differ between machines.
+var (
+	excludedDirs  = map[string]bool{".git": true, ".hg": true, ".svn": true, "__pycache__": true}
+	excludedFiles = map[string]bool{".DS_Store": true, "Thumbs.db": true}
+)
+
+// zipEpoch is the timestamp of every entry: 1980-01-01 00:00, the earliest
+// date the zip format stores, in MS-DOS date encoding.
+const zipEpoch = 1<<5 | 1
+
+// File is a file of a source directory.
+type File struct {
+	// Name is the slash-separated path relative to the source directory
+	Name string
+	// Path is the file on disk, after resolving symlinks
+	Path       string
+	Executable bool
+}
+
+// Archive is a packaged source directory.
+type Archive struct {
+	Path string
+	// SHA256 is the hex digest of the archive
+	SHA256 string
+	Files  int
+	Size   int64
+}
+
+// Files returns the files of dir that are packaged, sorted by name. Symlinks
+// to files are packaged as the file they point to; symlinked directories are
+// rejected since they may point outside dir or form loops.
+func Files(dir string) ([]File, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	var files []File
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && excludedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if excludedFiles[entry.Name()] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		target := path
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if target, err = filepath.EvalSymlinks(path); err != nil {
+				return fmt.Errorf("error resolving symlink %s: %w", name, err)
+			}
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			return fmt.Errorf("%s is a symlinked directory, which is not supported", name)
+		case !info.Mode().IsRegular():
+			return fmt.Errorf("%s is not a regular file", name)
+		}
+
+		files = append(files, File{Name: name, Path: target, Executable: info.Mode().Perm()&0111 != 0})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// Write writes the zip archive of dir to w and returns the number of files
+// in it. Entries carry a fixed timestamp and mode 0644, or 0755 for
+// executable files, so the archive only depends on names, contents and the
+// executable bit.
+func Write(w io.Writer, dir string) (int, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("%s has no files to package", dir)
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		header := &zip.FileHeader{
+			Name:         file.Name,
+			Method:       zip.Deflate,
+			ModifiedDate: zipEpoch,
+		}
+		mode := fs.FileMode(0644)
+		if file.Executable {
+			mode = 0755
+		}
+		header.SetMode(mode)
+
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return 0, err
+		}
+		if err := copyFile(entry, file.Path); err != nil {
+			return 0, fmt.Errorf("error packaging %s: %w", file.Name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Build packages dir into outDir as <name>-<digest>.zip and removes the
+// archives of earlier builds. The digest in the file name changes with the
+// content, so modules redeploy whenever the code changes.
+func Build(dir, outDir, name string) (*Archive, error) {
+	tmp, err := os.CreateTemp(outDir, name+"-*.zip.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	files, err := Write(io.MultiWriter(tmp, hash), dir)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	path := filepath.Join(outDir, fmt.Sprintf("%s-%s.zip", name, digest[:12]))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := filepath.Glob(filepath.Join(outDir, name+"-*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	for _, old := range stale {
+		if old != path {
+			if err := os.Remove(old); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Archive{Path: path, SHA256: digest, Files: files, Size: info.Size()}, nil
+}
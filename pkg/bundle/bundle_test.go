@@ -0,0 +1,174 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriteIsDeterministic(t *testing.T) {
+	files := map[string]string{
+		"main.py":                   "def handler(request):\n    return 'ok'\n",
+		"requirements.txt":          "requests==2.32.3\n",
+		"lib/util.py":               "VALUE = 1\n",
+		"lib/__pycache__/util.pyc":  "cache",
+		".git/HEAD":                 "ref: refs/heads/main\n",
+		".DS_Store":                 "finder",
+		"static/nested/index.html":  "<html></html>\n",
+		"static/nested/favicon.ico": "\x00\x01\x02",
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	writeFiles(t, first, files)
+	writeFiles(t, second, files)
+
+	// Timestamps and permissions other than the executable bit must not matter
+	old := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(second, "main.py"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(second, "lib", "util.py"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var a, b bytes.Buffer
+	if _, err := Write(&a, first); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Write(&b, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("archives of the same files differ")
+	}
+	if n != 5 {
+		t.Errorf("packaged %d files, want 5", n)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(a.Bytes()), int64(a.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range reader.File {
+		names = append(names, entry.Name)
+		if !entry.Modified.Equal(time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: modified %v, want 1980-01-01", entry.Name, entry.Modified)
+		}
+		if mode := entry.Mode().Perm(); mode != 0644 {
+			t.Errorf("%s: mode %#o, want 0644", entry.Name, mode)
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != files[entry.Name] {
+			t.Errorf("%s: content %q, want %q", entry.Name, content, files[entry.Name])
+		}
+	}
+	want := []string{"lib/util.py", "main.py", "requirements.txt", "static/nested/favicon.ico", "static/nested/index.html"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+}
+
+func TestWriteKeepsExecutableBit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable bit on Windows")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"bootstrap": "#!/bin/sh\n"})
+	if err := os.Chmod(filepath.Join(dir, "bootstrap"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Write(&buf, dir); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := reader.File[0].Mode().Perm(); mode != 0755 {
+		t.Errorf("mode %#o, want 0755", mode)
+	}
+}
+
+func TestWriteRejects(t *testing.T) {
+	empty := t.TempDir()
+	writeFiles(t, empty, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+	if _, err := Write(io.Discard, empty); err == nil {
+		t.Error("expected an error for a directory without files to package")
+	}
+
+	if runtime.GOOS != "windows" {
+		linked := t.TempDir()
+		writeFiles(t, linked, map[string]string{"main.py": ""})
+		if err := os.Symlink(t.TempDir(), filepath.Join(linked, "shared")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Write(io.Discard, linked); err == nil {
+			t.Error("expected an error for a symlinked directory")
+		}
+	}
+}
+
+func TestBuildReplacesEarlierArchives(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"index.js": "exports.handler = () => 1;\n"})
+
+	first, err := Build(src, out, "function")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Build(src, out, "function")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Path != first.Path || again.SHA256 != first.SHA256 {
+		t.Errorf("rebuilding unchanged sources gave %s, want %s", again.Path, first.Path)
+	}
+
+	writeFiles(t, src, map[string]string{"index.js": "exports.handler = () => 2;\n"})
+	changed, err := Build(src, out, "function")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Path == first.Path {
+		t.Error("changed sources kept the archive name")
+	}
+
+	archives, err := filepath.Glob(filepath.Join(out, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(archives, []string{changed.Path}) {
+		t.Errorf("output directory holds %v, want only %s", archives, changed.Path)
+	}
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SizeSpec describes what an abstract instance size means on each provider.
+type SizeSpec struct {
+	VCPU         int               `json:"vcpu"`
+	MemoryGB     float64           `json:"memory_gb"`
+	MachineTypes map[string]string `json:"machine_types"`
+	// DatabaseTiers are the instance classes of database.sql services
+	DatabaseTiers map[string]string `json:"database_tiers,omitempty"`
+	// NodeTypes are the node machine types of container.cluster services,
+	// which need more memory than the smallest instances have
+	NodeTypes map[string]string `json:"node_types,omitempty"`
+}
+
+// OSSpec describes the image an abstract OS maps to on each provider. Image
+// values are passed to the modules as they are.
+type OSSpec struct {
+	Name     string                 `json:"name"`
+	Images   map[string]interface{} `json:"images"`
+	SSHUsers map[string]string      `json:"ssh_users,omitempty"`
+}
+
+// EngineSpec describes a database engine. Versions map the engine versions
+// a database.sql service may request to each provider's version string.
+type EngineSpec struct {
+	Name           string                       `json:"name"`
+	DefaultVersion string                       `json:"default_version"`
+	Versions       map[string]map[string]string `json:"versions"`
+}
+
+// RuntimeSpec describes a function runtime by each provider's runtime name.
+// Azure names are "<stack>:<version>".
+type RuntimeSpec struct {
+	Name     string            `json:"name"`
+	Runtimes map[string]string `json:"runtimes"`
+}
+
+// Catalog is the versioned size and OS catalog (parser/catalog.json). It is
+// the only place abstract sizes and OS names are mapped to provider values.
+// MachineTypes lists the provider-native machine types a service may request
+// instead of a size. OSAliases map unversioned OS names to a pinned version.
+// Engines are the database engines and versions of database.sql services.
+// Runtimes are the runtimes of compute.function services.
+type Catalog struct {
+	Version      string                 `json:"version"`
+	Sizes        map[string]SizeSpec    `json:"sizes"`
+	MachineTypes map[string][]string    `json:"machine_types"`
+	OS           map[string]OSSpec      `json:"os"`
+	OSAliases    map[string]string      `json:"os_aliases,omitempty"`
+	Engines      map[string]EngineSpec  `json:"database_engines,omitempty"`
+	Runtimes     map[string]RuntimeSpec `json:"function_runtimes,omitempty"`
+}
+
+// LoadCatalog reads parser/catalog.json below rootPath.
+func LoadCatalog(rootPath string) (*Catalog, error) {
+	path := filepath.Join(rootPath, "parser", "catalog.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading catalog from %s: %w", path, err)
+	}
+
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("error parsing catalog: %w", err)
+	}
+	return &catalog, nil
+}
+
+// SizeNames returns the sizes from smallest to largest.
+func (c *Catalog) SizeNames() []string {
+	names := make([]string, 0, len(c.Sizes))
+	for name := range c.Sizes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.Sizes[names[i]], c.Sizes[names[j]]
+		if a.VCPU != b.VCPU {
+			return a.VCPU < b.VCPU
+		}
+		if a.MemoryGB != b.MemoryGB {
+			return a.MemoryGB < b.MemoryGB
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// OSNames returns the OS names in sorted order. Aliases are not included.
+func (c *Catalog) OSNames() []string {
+	names := make([]string, 0, len(c.OS))
+	for name := range c.OS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OSAliasNames returns the OS aliases in sorted order.
+func (c *Catalog) OSAliasNames() []string {
+	names := make([]string, 0, len(c.OSAliases))
+	for name := range c.OSAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EngineNames returns the database engines in sorted order.
+func (c *Catalog) EngineNames() []string {
+	names := make([]string, 0, len(c.Engines))
+	for name := range c.Engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EngineVersions returns the versions of an engine in sorted order.
+func (c *Catalog) EngineVersions(engine string) []string {
+	versions := make([]string, 0, len(c.Engines[engine].Versions))
+	for version := range c.Engines[engine].Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// RuntimeNames returns the function runtimes in sorted order.
+func (c *Catalog) RuntimeNames() []string {
+	names := make([]string, 0, len(c.Runtimes))
+	for name := range c.Runtimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// osSpec looks up an OS by name or alias.
+func (c *Catalog) osSpec(name string) (OSSpec, bool) {
+	if target, ok := c.OSAliases[name]; ok {
+		name = target
+	}
+	spec, ok := c.OS[name]
+	return spec, ok
+}
+
+// machineType validates a provider-native machine type against the catalog.
+// Azure sizes are case-insensitive and are returned in catalog spelling.
+func (c *Catalog) machineType(provider, machineType string) (string, error) {
+	best, bestDistance := "", 4
+	for _, known := range c.MachineTypes[provider] {
+		if strings.EqualFold(known, machineType) && (provider == "azure" || known == machineType) {
+			return known, nil
+		}
+		if d := editDistance(strings.ToLower(machineType), strings.ToLower(known)); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+
+	msg := fmt.Sprintf("machine type %q is not in the %s catalog", machineType, provider)
+	if best != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", best)
+	}
+	return "", fmt.Errorf("%s; run 'provisioner catalog %s' for the list", msg, provider)
+}
+
+// serviceValues resolves the catalog entries of a service into the values
+// generator_config.json reads with "source": "catalog". On error it also
+// returns the service field at fault.
+func (c *Catalog) serviceValues(provider string, service Service) (map[string]interface{}, string, error) {
+	switch service.Type {
+	case "database.sql":
+		return c.databaseValues(provider, service)
+	case "compute.function":
+		runtime, ok := c.Runtimes[service.Runtime].Runtimes[provider]
+		if !ok {
+			return nil, "runtime", fmt.Errorf("runtime %q is not available on %s", service.Runtime, provider)
+		}
+		return map[string]interface{}{"runtime": runtime}, "", nil
+	}
+	values := map[string]interface{}{}
+
+	if service.Type == "container.cluster" && service.Size != "" {
+		nodeType, ok := c.Sizes[service.Size].NodeTypes[provider]
+		if !ok {
+			return nil, "size", fmt.Errorf("size %q is not available for clusters on %s", service.Size, provider)
+		}
+		values["machine_type"] = nodeType
+	} else if service.MachineType != "" {
+		machineType, err := c.machineType(provider, service.MachineType)
+		if err != nil {
+			return nil, "machine_type", err
+		}
+		values["machine_type"] = machineType
+	} else if service.Size != "" {
+		machineType, ok := c.Sizes[service.Size].MachineTypes[provider]
+		if !ok {
+			return nil, "size", fmt.Errorf("size %q is not available on %s", service.Size, provider)
+		}
+		values["machine_type"] = machineType
+	}
+
+	if service.Image != "" {
+		image, err := parseImage(provider, service.Image)
+		if err != nil {
+			return nil, "image", err
+		}
+		values["image"] = image
+		// The login user of a custom image can't be derived from the catalog
+		if service.AdminUsername != "" {
+			values["ssh_user"] = service.AdminUsername
+		}
+	} else if service.OS != "" {
+		spec, _ := c.osSpec(service.OS)
+		image, ok := spec.Images[provider]
+		if !ok {
+			return nil, "os", fmt.Errorf("os %q is not available on %s", service.OS, provider)
+		}
+		values["image"] = image
+		if user, ok := spec.SSHUsers[provider]; ok {
+			values["ssh_user"] = user
+		}
+	}
+	return values, "", nil
+}
+
+// databaseValues resolves the size and engine version of a database.sql
+// service into the tier and engine_version module inputs.
+func (c *Catalog) databaseValues(provider string, service Service) (map[string]interface{}, string, error) {
+	tier, ok := c.Sizes[service.Size].DatabaseTiers[provider]
+	if !ok {
+		return nil, "size", fmt.Errorf("size %q is not available for databases on %s", service.Size, provider)
+	}
+
+	spec := c.Engines[service.Engine]
+	version := service.EngineVersion
+	if version == "" {
+		version = spec.DefaultVersion
+	}
+	versions, ok := spec.Versions[version]
+	if !ok {
+		return nil, "engine_version", fmt.Errorf("%s version %q is not in the catalog (available: %s)", spec.Name, version, strings.Join(c.EngineVersions(service.Engine), ", "))
+	}
+	providerVersion, ok := versions[provider]
+	if !ok {
+		return nil, "engine_version", fmt.Errorf("%s %s is not available on %s", spec.Name, version, provider)
+	}
+
+	return map[string]interface{}{"tier": tier, "engine_version": providerVersion}, "", nil
+}
+
+// injectEnums sets the size, os, engine and runtime enums of the schema from the catalog.
+func (c *Catalog) injectEnums(schema map[string]interface{}) {
+	props, ok := nestedMap(schema, "properties", "services", "items", "properties")
+	if !ok {
+		return
+	}
+	osNames := append(c.OSNames(), c.OSAliasNames()...)
+	for field, names := range map[string][]string{"size": c.SizeNames(), "os": osNames, "engine": c.EngineNames(), "runtime": c.RuntimeNames()} {
+		if prop, ok := props[field].(map[string]interface{}); ok {
+			prop["enum"] = names
+		}
+	}
+}
+
+func nestedMap(m map[string]interface{}, keys ...string) (map[string]interface{}, bool) {
+	for _, key := range keys {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	return m, true
+}
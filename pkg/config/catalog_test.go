@@ -0,0 +1,129 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testCatalog = &Catalog{
+	Version: "test",
+	Sizes: map[string]SizeSpec{
+		"small": {
+			VCPU: 2, MemoryGB: 1,
+			MachineTypes:  map[string]string{"aws": "t3.micro", "azure": "Standard_B2ats_v2", "gcp": "e2-micro"},
+			DatabaseTiers: map[string]string{"aws": "db.t4g.micro", "gcp": "db-g1-small"},
+			NodeTypes:     map[string]string{"aws": "t3.medium"},
+		},
+		"large": {
+			VCPU: 4, MemoryGB: 16,
+			MachineTypes: map[string]string{"aws": "m7i-flex.xlarge"},
+		},
+	},
+	MachineTypes: map[string][]string{
+		"aws":   {"t3.micro", "t3.small", "c7i.large"},
+		"azure": {"Standard_B2ats_v2", "Standard_D2as_v5"},
+	},
+	OS: map[string]OSSpec{
+		"ubuntu-24.04": {
+			Name: "Ubuntu 24.04 LTS",
+			Images: map[string]interface{}{
+				"aws": map[string]interface{}{"owner": "099720109477", "name": "ubuntu-noble-*"},
+				"gcp": "ubuntu-os-cloud/ubuntu-2404-lts-amd64",
+			},
+			SSHUsers: map[string]string{"aws": "ubuntu"},
+		},
+	},
+	OSAliases: map[string]string{"ubuntu": "ubuntu-24.04"},
+	Engines: map[string]EngineSpec{
+		"postgres": {Name: "PostgreSQL", DefaultVersion: "16", Versions: map[string]map[string]string{
+			"16": {"aws": "16.4", "gcp": "POSTGRES_16"},
+			"15": {"aws": "15.8"},
+		}},
+	},
+	Runtimes: map[string]RuntimeSpec{
+		"python3.12": {Name: "Python 3.12", Runtimes: map[string]string{"aws": "python3.12", "azure": "python:3.12"}},
+	},
+}
+
+func TestCatalogMachineType(t *testing.T) {
+	tests := []struct {
+		provider, machineType string
+		want                  string
+		err                   string
+	}{
+		{provider: "aws", machineType: "t3.small", want: "t3.small"},
+		{provider: "aws", machineType: "T3.small", err: `machine type "T3.small" is not in the aws catalog (did you mean "t3.small"?)`},
+		{provider: "aws", machineType: "t3.smal", err: `(did you mean "t3.small"?)`},
+		{provider: "aws", machineType: "x1e.32xlarge", err: `machine type "x1e.32xlarge" is not in the aws catalog; run 'provisioner catalog aws' for the list`},
+		{provider: "azure", machineType: "standard_d2as_v5", want: "Standard_D2as_v5"},
+		{provider: "gcp", machineType: "e2-micro", err: `machine type "e2-micro" is not in the gcp catalog`},
+	}
+	for _, tt := range tests {
+		got, err := testCatalog.machineType(tt.provider, tt.machineType)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("machineType(%s, %q): error %v, want %q", tt.provider, tt.machineType, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("machineType(%s, %q) = %q, %v, want %q", tt.provider, tt.machineType, got, err, tt.want)
+		}
+	}
+}
+
+func TestCatalogServiceValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		service  Service
+		want     map[string]interface{}
+		field    string
+	}{
+		{"size and OS", "aws", Service{Type: "compute.instance", Size: "small", OS: "ubuntu-24.04"}, map[string]interface{}{
+			"machine_type": "t3.micro",
+			"image":        map[string]interface{}{"owner": "099720109477", "name": "ubuntu-noble-*"},
+			"ssh_user":     "ubuntu",
+		}, ""},
+		{"OS alias", "gcp", Service{Type: "compute.instance", Size: "small", OS: "ubuntu"}, map[string]interface{}{
+			"machine_type": "e2-micro",
+			"image":        "ubuntu-os-cloud/ubuntu-2404-lts-amd64",
+		}, ""},
+		{"machine type instead of size", "azure", Service{Type: "compute.instance", MachineType: "standard_b2ats_v2"}, map[string]interface{}{
+			"machine_type": "Standard_B2ats_v2",
+		}, ""},
+		{"custom image with its login user", "aws", Service{Type: "compute.instance", Size: "large", Image: "ami-01234567", AdminUsername: "ec2-user"}, map[string]interface{}{
+			"machine_type": "m7i-flex.xlarge",
+			"image":        map[string]interface{}{"id": "ami-01234567"},
+			"ssh_user":     "ec2-user",
+		}, ""},
+		{"bucket without catalog values", "aws", Service{Type: "storage.object"}, map[string]interface{}{}, ""},
+		{"size missing on provider", "gcp", Service{Type: "compute.instance", Size: "large"}, nil, "size"},
+		{"OS missing on provider", "azure", Service{Type: "compute.instance", Size: "small", OS: "ubuntu-24.04"}, nil, "os"},
+		{"unknown machine type", "aws", Service{Type: "compute.instance", MachineType: "t9.huge"}, nil, "machine_type"},
+		{"invalid image", "aws", Service{Type: "compute.instance", Size: "small", Image: "ubuntu"}, nil, "image"},
+		{"cluster node type", "aws", Service{Type: "container.cluster", Size: "small"}, map[string]interface{}{"machine_type": "t3.medium"}, ""},
+		{"cluster size missing on provider", "aws", Service{Type: "container.cluster", Size: "large"}, nil, "size"},
+		{"database default version", "aws", Service{Type: "database.sql", Size: "small", Engine: "postgres"}, map[string]interface{}{
+			"tier": "db.t4g.micro", "engine_version": "16.4",
+		}, ""},
+		{"database size missing on provider", "azure", Service{Type: "database.sql", Size: "small", Engine: "postgres"}, nil, "size"},
+		{"database version missing on provider", "gcp", Service{Type: "database.sql", Size: "small", Engine: "postgres", EngineVersion: "15"}, nil, "engine_version"},
+		{"unknown database version", "aws", Service{Type: "database.sql", Size: "small", Engine: "postgres", EngineVersion: "9"}, nil, "engine_version"},
+		{"function runtime", "azure", Service{Type: "compute.function", Runtime: "python3.12"}, map[string]interface{}{"runtime": "python:3.12"}, ""},
+		{"runtime missing on provider", "gcp", Service{Type: "compute.function", Runtime: "python3.12"}, nil, "runtime"},
+	}
+	for _, tt := range tests {
+		got, field, err := testCatalog.serviceValues(tt.provider, tt.service)
+		if tt.field != "" {
+			if err == nil || field != tt.field {
+				t.Errorf("%s: field %q, error %v, want an error for %q", tt.name, field, err, tt.field)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: serviceValues = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// safeIDRe restricts resource IDs to names usable as directories on every OS.
+var safeIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// cloudName is a name a module derives from a resource ID. Two resources
+// deriving the same name in the same scope collide in the cloud.
+type cloudName struct {
+	Scope string
+	Kind  string
+	Name  string
+}
+
+// cloudNames mirrors the naming in the opentofu modules. Names are compared
+// lowercased: bucket names are lowercased by the modules and Azure names are
+// case-insensitive.
+func cloudNames(res ResourcePlan) []cloudName {
+	id := strings.ToLower(res.ID)
+
+	switch res.Provider + "/" + res.Type {
+	case "aws/compute.instance":
+		return []cloudName{{Scope: "aws/" + res.Region, Kind: "key pair", Name: id + "-key"}}
+	case "aws/compute.function":
+		return []cloudName{
+			{Scope: "aws/" + res.Region, Kind: "Lambda function", Name: id},
+			{Scope: "aws", Kind: "IAM role", Name: id + "-role"},
+		}
+	case "aws/container.cluster":
+		return []cloudName{
+			{Scope: "aws/" + res.Region, Kind: "EKS cluster", Name: id},
+			{Scope: "aws", Kind: "IAM role", Name: id + "-cluster"},
+			{Scope: "aws", Kind: "IAM role", Name: id + "-nodes"},
+		}
+	case "aws/database.sql":
+		return []cloudName{
+			{Scope: "aws/" + res.Region, Kind: "RDS instance", Name: id},
+			{Scope: "aws/" + res.Region, Kind: "security group", Name: id + "-db"},
+		}
+	case "aws/network.load_balancer":
+		return []cloudName{
+			{Scope: "aws/" + res.Region, Kind: "load balancer", Name: id},
+			{Scope: "aws/" + res.Region, Kind: "security group", Name: id + "-lb"},
+		}
+	case "aws/storage.object":
+		return []cloudName{{Scope: "aws", Kind: "S3 bucket", Name: id}}
+	case "gcp/compute.instance":
+		return []cloudName{
+			{Scope: "gcp/" + res.Region, Kind: "instance", Name: id},
+			{Scope: "gcp", Kind: "firewall rules", Name: id + "-fw<n>"},
+		}
+	case "gcp/compute.function":
+		return []cloudName{{Scope: "gcp/" + res.Region, Kind: "Cloud Function", Name: id}}
+	case "gcp/container.cluster":
+		return []cloudName{{Scope: "gcp", Kind: "GKE cluster", Name: id}}
+	case "gcp/database.sql":
+		return []cloudName{{Scope: "gcp", Kind: "Cloud SQL instance", Name: id}}
+	case "gcp/network.load_balancer":
+		return []cloudName{
+			{Scope: "gcp", Kind: "load balancer", Name: id},
+			{Scope: "gcp", Kind: "firewall rule", Name: id + "-hc"},
+		}
+	case "gcp/network.vpc":
+		return []cloudName{{Scope: "gcp", Kind: "VPC network", Name: id}}
+	case "gcp/storage.object":
+		return []cloudName{{Scope: "gcp", Kind: "GCS bucket", Name: id}}
+	case "azure/compute.instance":
+		return []cloudName{{Scope: "azure", Kind: "resource group", Name: id + "-rg"}}
+	case "azure/compute.function":
+		return []cloudName{
+			{Scope: "azure", Kind: "resource group", Name: id + "-rg"},
+			{Scope: "azure", Kind: "function app", Name: id},
+		}
+	case "azure/container.cluster":
+		return []cloudName{{Scope: "azure", Kind: "resource group", Name: id + "-rg"}}
+	case "azure/database.sql":
+		return []cloudName{
+			{Scope: "azure", Kind: "resource group", Name: id + "-rg"},
+			{Scope: "azure", Kind: "database server", Name: id},
+		}
+	case "azure/network.load_balancer":
+		return []cloudName{{Scope: "azure", Kind: "resource group", Name: id + "-rg"}}
+	case "azure/network.vpc":
+		return []cloudName{{Scope: "azure", Kind: "resource group", Name: id + "-rg"}}
+	case "azure/storage.object":
+		// The storage account name is the ID itself: the naming rule rejects
+		// the characters the module strips
+		return []cloudName{{Scope: "azure", Kind: "resource group", Name: id + "-rg"}}
+	}
+	return nil
+}
+
+// checkResourceIDs reports resources sharing a directory, IDs that are not
+// safe directory names and IDs the modules turn into the same cloud resource
+// name. locations[i] is the config location of the ID of resources[i].
+func checkResourceIDs(resources []ResourcePlan, locations []string) error {
+	var problems []string
+
+	dirs := map[string]int{}
+	names := map[cloudName]int{}
+	for i, res := range resources {
+		if !safeIDRe.MatchString(res.ID) {
+			problems = append(problems, fmt.Sprintf("%s: ID %q is not a safe directory name (use letters, digits, '.', '_' and '-', starting with a letter or digit)", locations[i], res.ID))
+		}
+
+		// Directories are compared case-insensitively for macOS and Windows
+		dir := strings.ToLower(res.ID)
+		if j, exists := dirs[dir]; exists {
+			problems = append(problems, fmt.Sprintf("%s: ID %q is already used by %s (resources would share one directory)", locations[i], res.ID, locations[j]))
+			continue
+		}
+		dirs[dir] = i
+
+		for _, name := range cloudNames(res) {
+			if j, exists := names[name]; exists {
+				problems = append(problems, fmt.Sprintf("%s: %s %q of %q collides with the one of %q (%s)", locations[i], name.Kind, name.Name, res.ID, resources[j].ID, locations[j]))
+				continue
+			}
+			names[name] = i
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
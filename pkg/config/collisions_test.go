@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckResourceIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []ResourcePlan
+		want      []string
+	}{
+		{"distinct IDs", []ResourcePlan{
+			{ID: "web", Type: "compute.instance", Provider: "aws", Region: "eu-west-1"},
+			{ID: "web-eu", Type: "compute.instance", Provider: "aws", Region: "eu-west-1"},
+			{ID: "assets", Type: "storage.object", Provider: "azure", Region: "swedencentral"},
+		}, nil},
+		{"same ID on different providers", []ResourcePlan{
+			{ID: "web", Type: "compute.instance", Provider: "aws", Region: "eu-west-1"},
+			{ID: "web", Type: "compute.instance", Provider: "gcp", Region: "europe-west1"},
+		}, []string{`services.1: ID "web" is already used by services.0`}},
+		{"directories are case-insensitive", []ResourcePlan{
+			{ID: "Logs", Type: "storage.object", Provider: "aws", Region: "eu-west-1"},
+			{ID: "logs", Type: "storage.object", Provider: "gcp", Region: "europe-west1"},
+		}, []string{`services.1: ID "logs" is already used by services.0`}},
+		{"unsafe IDs", []ResourcePlan{
+			{ID: "../web", Type: "compute.instance", Provider: "aws", Region: "eu-west-1"},
+			{ID: "-web", Type: "compute.instance", Provider: "aws", Region: "eu-west-1"},
+		}, []string{`services.0: ID "../web" is not a safe directory name`, `services.1: ID "-web" is not a safe directory name`}},
+	}
+	for _, tt := range tests {
+		locations := make([]string, len(tt.resources))
+		for i := range locations {
+			locations[i] = fmt.Sprintf("services.%d", i)
+		}
+
+		err := checkResourceIDs(tt.resources, locations)
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if n := strings.Count(err.Error(), "\n  - "); n != len(tt.want) {
+			t.Errorf("%s: %d problems, want %d: %v", tt.name, n, len(tt.want), err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not contain %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestCloudNamesAreLowercased(t *testing.T) {
+	names := cloudNames(ResourcePlan{ID: "Data", Type: "storage.object", Provider: "aws"})
+	if len(names) != 1 || names[0].Name != "data" {
+		t.Errorf("cloudNames = %v, want the S3 bucket \"data\"", names)
+	}
+}
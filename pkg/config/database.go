@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// databaseUserRe keeps admin usernames valid on every provider; MySQL on RDS
+// allows at most 16 characters.
+var databaseUserRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,15}$`)
+
+// reservedDatabaseUsers are admin usernames at least one provider rejects.
+var reservedDatabaseUsers = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "guest": true, "public": true,
+	"postgres": true, "mysql": true, "rdsadmin": true, "cloudsqladmin": true,
+	"azure_superuser": true, "azure_pg_admin": true, "sa": true,
+}
+
+// minBackupRetentionDays is the shortest backup retention a provider
+// accepts; backups can only be turned off (0 days) where it is 0.
+var minBackupRetentionDays = map[string]int{
+	"aws":   0,
+	"azure": 7,
+	"gcp":   0,
+}
+
+// validateDatabase checks the settings of the i-th service, a database.sql
+// service on provider.
+func validateDatabase(doc *document, i int, provider string, service Service) []string {
+	var problems []string
+	report := func(field string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err))
+	}
+
+	switch {
+	case service.PublicAccess && len(service.AllowedCIDRs) == 0:
+		report("allowed_cidrs", fmt.Errorf("public databases need allowed_cidrs (use [\"%s\"] to allow anywhere)", anywhere))
+	case !service.PublicAccess && len(service.AllowedCIDRs) > 0:
+		report("allowed_cidrs", fmt.Errorf("allowed_cidrs can only be set when public_access is true"))
+	}
+	for j, cidr := range service.AllowedCIDRs {
+		if err := checkCIDR(cidr); err != nil {
+			report(fmt.Sprintf("allowed_cidrs.%d", j), err)
+		}
+	}
+
+	if days := service.BackupRetentionDays; days != nil && *days < minBackupRetentionDays[provider] {
+		report("backup_retention_days", fmt.Errorf("%s keeps backups for at least %d days", provider, minBackupRetentionDays[provider]))
+	}
+
+	if user := service.AdminUsername; user != "" {
+		if !databaseUserRe.MatchString(user) {
+			report("admin_username", fmt.Errorf("database admin username %q must be 1-16 lowercase letters, digits and '_', starting with a letter", user))
+		} else if reservedDatabaseUsers[user] {
+			report("admin_username", fmt.Errorf("database admin username %q is reserved", user))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDatabase(t *testing.T) {
+	days := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		provider string
+		service  Service
+		want     []string
+	}{
+		{"private", "aws", Service{AdminUsername: "app_admin", BackupRetentionDays: days(0)}, nil},
+		{"public with allowed CIDRs", "gcp", Service{PublicAccess: true, AllowedCIDRs: []string{"203.0.113.0/24"}}, nil},
+		{"public without allowed CIDRs", "aws", Service{PublicAccess: true}, []string{"public databases need allowed_cidrs"}},
+		{"allowed CIDRs on a private database", "aws", Service{AllowedCIDRs: []string{"203.0.113.0/24"}}, []string{"allowed_cidrs can only be set when public_access is true"}},
+		{"invalid CIDR", "aws", Service{PublicAccess: true, AllowedCIDRs: []string{"203.0.113.1/24"}}, []string{"has host bits set"}},
+		{"Azure keeps backups for a week", "azure", Service{BackupRetentionDays: days(3)}, []string{"azure keeps backups for at least 7 days"}},
+		{"Azure backups", "azure", Service{BackupRetentionDays: days(7)}, nil},
+		{"invalid admin username", "aws", Service{AdminUsername: "App-Admin"}, []string{`database admin username "App-Admin" must be 1-16 lowercase letters`}},
+		{"long admin username", "aws", Service{AdminUsername: "administrator_one"}, []string{"must be 1-16 lowercase letters"}},
+		{"reserved admin username", "gcp", Service{AdminUsername: "postgres"}, []string{`database admin username "postgres" is reserved`}},
+	}
+	doc := &document{Path: "config.json"}
+	for _, tt := range tests {
+		problems := validateDatabase(doc, 0, tt.provider, tt.service)
+		if len(problems) != len(tt.want) {
+			t.Errorf("%s: got %q, want %d problems", tt.name, problems, len(tt.want))
+			continue
+		}
+		for j, want := range tt.want {
+			if !strings.Contains(problems[j], want) {
+				t.Errorf("%s: problem %q does not contain %q", tt.name, problems[j], want)
+			}
+		}
+	}
+}
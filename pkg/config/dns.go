@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RecordSource takes the value of a dns.record from an output of another
+// resource, e.g. the public_ip of a compute instance.
+type RecordSource struct {
+	Resource string `json:"resource"`
+	Output   string `json:"output"`
+}
+
+var (
+	zoneLabelRe   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	recordLabelRe = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?$`)
+)
+
+// maxTXTLength is the longest character string a TXT record holds.
+const maxTXTLength = 255
+
+// checkHostname checks a domain name of at least minLabels labels. A trailing
+// dot is accepted when trailingDot is set.
+func checkHostname(name string, minLabels int, trailingDot bool, labelRe *regexp.Regexp) error {
+	if trailingDot {
+		name = strings.TrimSuffix(name, ".")
+	}
+	if name != strings.ToLower(name) {
+		return fmt.Errorf("%q must be lowercase", name)
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("%q is longer than 253 characters", name)
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) < minLabels {
+		return fmt.Errorf("%q is not a fully qualified domain name", name)
+	}
+	for _, label := range labels {
+		if !labelRe.MatchString(label) {
+			return fmt.Errorf("%q has an invalid label %q (1-63 letters, digits and '-', not starting or ending with '-')", name, label)
+		}
+	}
+	return nil
+}
+
+// validateRecord checks the name, type and values of the i-th service, a
+// dns.record service.
+func validateRecord(doc *document, i int, service Service) []string {
+	var problems []string
+	report := func(field string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err))
+	}
+
+	// Records are global; copies per region would manage the same record
+	if len(service.Regions) > 0 {
+		report("regions", fmt.Errorf("dns.record services cannot be copied across regions"))
+	}
+
+	zone := service.DNSZone
+	if strings.HasSuffix(zone, ".") {
+		report("dns_zone", fmt.Errorf("write the zone without the trailing dot (%q)", strings.TrimSuffix(zone, ".")))
+	} else if err := checkHostname(zone, 2, false, zoneLabelRe); err != nil {
+		report("dns_zone", err)
+	}
+
+	name := service.RecordName
+	switch {
+	case name == "@":
+	case name == zone || strings.HasSuffix(name, "."+zone):
+		relative := strings.TrimSuffix(strings.TrimSuffix(name, zone), ".")
+		if relative == "" {
+			relative = "@"
+		}
+		report("record_name", fmt.Errorf("record_name is relative to the zone %q (did you mean %q?)", zone, relative))
+	default:
+		// A wildcard may only be the leftmost label
+		err := checkHostname(strings.TrimPrefix(name, "*."), 1, false, recordLabelRe)
+		if err == nil && len(name)+1+len(zone) > 253 {
+			err = fmt.Errorf("%q is longer than 253 characters", name+"."+zone)
+		}
+		if err != nil {
+			report("record_name", err)
+		}
+	}
+
+	switch {
+	case len(service.Values) > 0 && service.ValueFrom != nil:
+		report("value_from", fmt.Errorf("'values' and 'value_from' cannot both be set"))
+		return problems
+	case len(service.Values) == 0 && service.ValueFrom == nil:
+		report("values", fmt.Errorf("a %s record needs 'values' or 'value_from'", service.RecordType))
+		return problems
+	}
+
+	if service.ValueFrom != nil && service.RecordType != "A" && service.RecordType != "AAAA" && service.RecordType != "CNAME" {
+		report("value_from", fmt.Errorf("value_from is only supported for A, AAAA and CNAME records"))
+	}
+	if service.RecordType == "CNAME" {
+		if name == "@" {
+			report("record_name", fmt.Errorf("a CNAME record cannot be created at the zone apex"))
+		}
+		if len(service.Values) > 1 {
+			report("values", fmt.Errorf("a CNAME record has exactly one value"))
+		}
+	}
+
+	for j, value := range service.Values {
+		var err error
+		switch service.RecordType {
+		case "A":
+			if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+				err = fmt.Errorf("%q is not an IPv4 address", value)
+			}
+		case "AAAA":
+			if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+				err = fmt.Errorf("%q is not an IPv6 address", value)
+			}
+		case "CNAME":
+			err = checkHostname(value, 2, true, recordLabelRe)
+		case "MX":
+			priority, host, ok := strings.Cut(value, " ")
+			if n, convErr := strconv.Atoi(priority); !ok || convErr != nil || n < 0 || n > 65535 {
+				err = fmt.Errorf("%q is not an MX value like \"10 mail.example.com\"", value)
+			} else {
+				err = checkHostname(host, 2, true, recordLabelRe)
+			}
+		case "TXT":
+			if value == "" || len(value) > maxTXTLength {
+				err = fmt.Errorf("TXT values must be 1-%d characters long (has %d)", maxTXTLength, len(value))
+			}
+		}
+		if err != nil {
+			report(fmt.Sprintf("values.%d", j), err)
+		}
+	}
+	return problems
+}
+
+// linkRecords resolves the value_from references of dns.record services
+// into module inputs and dependencies. The referenced output must be declared
+// by the module of the resource below rootPath.
+func linkRecords(plan *ProvisioningPlan, services []Service, locations []string, rootPath string) error {
+	var problems []string
+	for i, service := range services {
+		if service.ValueFrom == nil {
+			continue
+		}
+		res := &plan.Resources[i]
+		from := service.ValueFrom
+
+		var source *ResourcePlan
+		for j := range plan.Resources {
+			if plan.Resources[j].ID == from.Resource {
+				source = &plan.Resources[j]
+			}
+		}
+		if source == nil {
+			problems = append(problems, fmt.Sprintf("%s: unknown resource %q", locations[i], from.Resource))
+			continue
+		}
+
+		outputs, err := ModuleOutputs(filepath.Join(rootPath, "opentofu", source.Provider, source.ModuleDir))
+		if err != nil {
+			return fmt.Errorf("error reading the outputs of %s: %w", source.ID, err)
+		}
+		var names []string
+		found := false
+		for _, output := range outputs {
+			if output.Sensitive {
+				continue
+			}
+			names = append(names, output.Name)
+			found = found || output.Name == from.Output
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %s has no output %q (outputs: %s)", locations[i], source.ID, from.Output, strings.Join(names, ", ")))
+			continue
+		}
+
+		res.DependsOn = append(res.DependsOn, source.ID)
+		if res.Inputs == nil {
+			res.Inputs = map[string]OutputRef{}
+		}
+		res.Inputs["records"] = OutputRef{Resource: source.ID, Output: from.Output, List: true}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// validateRecords reports dns.record services that manage the same record
+// set, or a CNAME next to other records of the same name, which the
+// providers reject.
+func validateRecords(doc *document, config Config) []string {
+	var problems []string
+	type recordSet struct {
+		provider, zone, name string
+	}
+	seen := map[recordSet]map[string]int{}
+
+	for i, service := range config.Services {
+		if service.Type != "dns.record" {
+			continue
+		}
+		set := recordSet{config.serviceProvider(service), service.DNSZone, service.RecordName}
+		location := doc.location(fmt.Sprintf("services.%d.record_name", i))
+		types := seen[set]
+		if types == nil {
+			types = map[string]int{}
+			seen[set] = types
+		}
+
+		if j, exists := types[service.RecordType]; exists {
+			problems = append(problems, fmt.Sprintf("%s: the %s record %q in %s is already managed by %s (put all values in one service)", location, service.RecordType, service.RecordName, service.DNSZone, doc.location(fmt.Sprintf("services.%d", j))))
+			continue
+		}
+		for recordType, j := range types {
+			if recordType == "CNAME" || service.RecordType == "CNAME" {
+				problems = append(problems, fmt.Sprintf("%s: %q in %s cannot have a CNAME and other records (see %s)", location, service.RecordName, service.DNSZone, doc.location(fmt.Sprintf("services.%d", j))))
+				break
+			}
+		}
+		types[service.RecordType] = i
+	}
+	return problems
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRecord(t *testing.T) {
+	record := func(name, recordType string, values ...string) Service {
+		return Service{DNSZone: "example.com", RecordName: name, RecordType: recordType, Values: values}
+	}
+	tests := []struct {
+		name    string
+		service Service
+		want    []string
+	}{
+		{"A record", record("www", "A", "203.0.113.10", "203.0.113.11"), nil},
+		{"apex", record("@", "A", "203.0.113.10"), nil},
+		{"wildcard", record("*.app", "CNAME", "lb.example.net."), nil},
+		{"MX", record("@", "MX", "10 mail.example.com"), nil},
+		{"value from an output", Service{DNSZone: "example.com", RecordName: "www", RecordType: "A", ValueFrom: &RecordSource{Resource: "web", Output: "public_ip"}}, nil},
+		{"zone with trailing dot", Service{DNSZone: "example.com.", RecordName: "www", RecordType: "A", Values: []string{"203.0.113.10"}}, []string{`write the zone without the trailing dot ("example.com")`}},
+		{"single-label zone", Service{DNSZone: "localhost", RecordName: "www", RecordType: "A", Values: []string{"203.0.113.10"}}, []string{"is not a fully qualified domain name"}},
+		{"absolute record name", record("www.example.com", "A", "203.0.113.10"), []string{`record_name is relative to the zone "example.com" (did you mean "www"?)`}},
+		{"uppercase record name", record("WWW", "A", "203.0.113.10"), []string{`"WWW" must be lowercase`}},
+		{"copies across regions", Service{DNSZone: "example.com", RecordName: "www", RecordType: "A", Values: []string{"203.0.113.10"}, Regions: []string{"eu-west-1"}}, []string{"cannot be copied across regions"}},
+		{"no values", record("www", "A"), []string{"a A record needs 'values' or 'value_from'"}},
+		{"values and value_from", Service{DNSZone: "example.com", RecordName: "www", RecordType: "A", Values: []string{"203.0.113.10"}, ValueFrom: &RecordSource{Resource: "web", Output: "public_ip"}}, []string{"'values' and 'value_from' cannot both be set"}},
+		{"value_from on MX", Service{DNSZone: "example.com", RecordName: "@", RecordType: "MX", ValueFrom: &RecordSource{Resource: "web", Output: "public_ip"}}, []string{"value_from is only supported for A, AAAA and CNAME records"}},
+		{"IPv6 in an A record", record("www", "A", "2001:db8::1"), []string{`"2001:db8::1" is not an IPv4 address`}},
+		{"IPv4 in an AAAA record", record("www", "AAAA", "203.0.113.10"), []string{`"203.0.113.10" is not an IPv6 address`}},
+		{"CNAME at the apex", record("@", "CNAME", "lb.example.net"), []string{"a CNAME record cannot be created at the zone apex"}},
+		{"CNAME with two values", record("www", "CNAME", "a.example.net", "b.example.net"), []string{"a CNAME record has exactly one value"}},
+		{"MX without priority", record("@", "MX", "mail.example.com"), []string{`is not an MX value like "10 mail.example.com"`}},
+		{"long TXT value", record("@", "TXT", strings.Repeat("x", 256)), []string{"TXT values must be 1-255 characters long (has 256)"}},
+	}
+	doc := &document{Path: "config.json"}
+	for _, tt := range tests {
+		problems := validateRecord(doc, 0, tt.service)
+		if len(problems) != len(tt.want) {
+			t.Errorf("%s: got %q, want %d problems", tt.name, problems, len(tt.want))
+			continue
+		}
+		for j, want := range tt.want {
+			if !strings.Contains(problems[j], want) {
+				t.Errorf("%s: problem %q does not contain %q", tt.name, problems[j], want)
+			}
+		}
+	}
+}
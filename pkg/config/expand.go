@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// iterRefRe matches the expressions available inside services using count or
+// for_each. each.value.<key> reads a field of an object value.
+var iterRefRe = regexp.MustCompile(`^(count\.index|each\.key|each\.value)(?:\.([A-Za-z0-9_-]+))?$`)
+
+// iteration is one copy of a service using count or for_each.
+type iteration struct {
+	// Kind is "count" or "for_each"
+	Kind  string
+	Index int
+	Key   string
+	Value interface{}
+}
+
+// suffix is appended to IDs that do not reference the iteration themselves.
+func (it *iteration) suffix() string {
+	if it.Kind == "count" {
+		return strconv.Itoa(it.Index)
+	}
+	return it.Key
+}
+
+// expandServices replaces services using count or for_each with one copy per
+// index or key and evaluates ${count.index}, ${each.key} and ${each.value} in
+// them. IDs that do not use these get "-<index>" or "-<key>" appended, so
+// existing copies keep their names when the count or keys change.
+func expandServices(doc *document) error {
+	services, ok := doc.Data["services"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var expanded []interface{}
+	var sources []int
+	for i, item := range services {
+		field := joinField("services", strconv.Itoa(i))
+		service, ok := item.(map[string]interface{})
+		if !ok {
+			expanded = append(expanded, item)
+			sources = append(sources, i)
+			continue
+		}
+
+		iterations, err := serviceIterations(doc, service, field)
+		if err != nil {
+			return err
+		}
+		if iterations == nil {
+			// References outside count/for_each services are errors
+			if _, err := substituteIteration(doc, service, nil, field); err != nil {
+				return err
+			}
+			expanded = append(expanded, service)
+			sources = append(sources, i)
+			continue
+		}
+
+		delete(service, "count")
+		delete(service, "for_each")
+		for _, it := range iterations {
+			copied, err := substituteIteration(doc, deepCopy(service), &it, field)
+			if err != nil {
+				return err
+			}
+			stamped := copied.(map[string]interface{})
+			for _, idField := range idFields {
+				if id, ok := stamped[idField].(string); ok && id != "" && id == service[idField] {
+					stamped[idField] = id + "-" + it.suffix()
+					if doc.copies == nil {
+						doc.copies = map[string][]string{}
+					}
+					doc.copies[id] = append(doc.copies[id], stamped[idField].(string))
+				}
+			}
+			expanded = append(expanded, stamped)
+			sources = append(sources, i)
+		}
+	}
+
+	if expanded == nil {
+		expanded = []interface{}{}
+	}
+	doc.Data["services"] = expanded
+	doc.remapServices(sources)
+	return nil
+}
+
+// serviceIterations returns the iterations of a service, or nil if it uses
+// neither count nor for_each.
+func serviceIterations(doc *document, service map[string]interface{}, field string) ([]iteration, error) {
+	count, hasCount := service["count"]
+	forEach, hasForEach := service["for_each"]
+
+	switch {
+	case hasCount && hasForEach:
+		return nil, fmt.Errorf("%s: 'count' and 'for_each' cannot both be set", doc.location(joinField(field, "for_each")))
+	case hasCount:
+		n, ok := count.(float64)
+		if !ok || n < 0 || n != float64(int(n)) {
+			return nil, fmt.Errorf("%s: 'count' must be a non-negative integer", doc.location(joinField(field, "count")))
+		}
+		iterations := make([]iteration, int(n))
+		for i := range iterations {
+			iterations[i] = iteration{Kind: "count", Index: i}
+		}
+		return iterations, nil
+	case hasForEach:
+		location := doc.location(joinField(field, "for_each"))
+		switch items := forEach.(type) {
+		case []interface{}:
+			iterations := make([]iteration, 0, len(items))
+			seen := map[string]bool{}
+			for i, item := range items {
+				key, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: 'for_each' lists must contain strings", location)
+				}
+				if seen[key] {
+					return nil, fmt.Errorf("%s: duplicate 'for_each' key %q", location, key)
+				}
+				seen[key] = true
+				iterations = append(iterations, iteration{Kind: "for_each", Index: i, Key: key, Value: key})
+			}
+			return iterations, nil
+		case map[string]interface{}:
+			keys := make([]string, 0, len(items))
+			for key := range items {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			iterations := make([]iteration, 0, len(keys))
+			for i, key := range keys {
+				iterations = append(iterations, iteration{Kind: "for_each", Index: i, Key: key, Value: items[key]})
+			}
+			return iterations, nil
+		default:
+			return nil, fmt.Errorf("%s: 'for_each' must be a list of strings or an object", location)
+		}
+	}
+	return nil, nil
+}
+
+// substituteIteration evaluates the iteration expressions in v. A nil it
+// reports any use of them as an error.
+func substituteIteration(doc *document, v interface{}, it *iteration, field string) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return substituteIterationString(doc, val, it, field)
+	case map[string]interface{}:
+		for k, item := range val {
+			child, err := substituteIteration(doc, item, it, joinField(field, k))
+			if err != nil {
+				return nil, err
+			}
+			val[k] = child
+		}
+	case []interface{}:
+		for i, item := range val {
+			child, err := substituteIteration(doc, item, it, joinField(field, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = child
+		}
+	}
+	return v, nil
+}
+
+func substituteIterationString(doc *document, s string, it *iteration, field string) (interface{}, error) {
+	matches := findExprs(s)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m[2] < 0 {
+			continue
+		}
+		expr := strings.TrimSpace(s[m[2]:m[3]])
+		if !iterRefRe.MatchString(expr) {
+			continue
+		}
+
+		value, err := iterationValue(doc, expr, it, field)
+		if err != nil {
+			return nil, err
+		}
+		if m[0] == 0 && m[1] == len(s) {
+			return value, nil
+		}
+
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("%s: cannot embed ${%s} in a string: value is not a string, number or bool", doc.location(field), expr)
+		case string:
+			b.WriteString(value.(string))
+		default:
+			encoded, _ := json.Marshal(value)
+			b.Write(encoded)
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+func iterationValue(doc *document, expr string, it *iteration, field string) (interface{}, error) {
+	m := iterRefRe.FindStringSubmatch(expr)
+	location := doc.location(field)
+
+	switch {
+	case it == nil && m[1] == "count.index":
+		return nil, fmt.Errorf("%s: ${%s} can only be used in a service with 'count'", location, expr)
+	case it == nil:
+		return nil, fmt.Errorf("%s: ${%s} can only be used in a service with 'for_each'", location, expr)
+	case m[1] == "count.index" && it.Kind != "count":
+		return nil, fmt.Errorf("%s: ${%s} requires 'count'; use ${each.key} with 'for_each'", location, expr)
+	case m[1] != "count.index" && it.Kind != "for_each":
+		return nil, fmt.Errorf("%s: ${%s} requires 'for_each'; use ${count.index} with 'count'", location, expr)
+	}
+
+	switch m[1] {
+	case "count.index":
+		if m[2] != "" {
+			return nil, fmt.Errorf("%s: unsupported expression ${%s}", location, expr)
+		}
+		return float64(it.Index), nil
+	case "each.key":
+		if m[2] != "" {
+			return nil, fmt.Errorf("%s: unsupported expression ${%s}", location, expr)
+		}
+		return it.Key, nil
+	}
+
+	if m[2] == "" {
+		return deepCopy(it.Value), nil
+	}
+	obj, ok := it.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: ${%s}: value of key %q is not an object", location, expr, it.Key)
+	}
+	value, ok := obj[m[2]]
+	if !ok {
+		return nil, fmt.Errorf("%s: ${%s}: value of key %q has no field %q", location, expr, it.Key, m[2])
+	}
+	return deepCopy(value), nil
+}
+
+// remapServices moves positions and secrets of services to their expanded
+// copies; sources[j] is the original index of the service now at index j.
+func (d *document) remapServices(sources []int) {
+	d.positions = remapServiceFields(d.positions, sources)
+	d.secrets = remapServiceFields(d.secrets, sources)
+}
+
+func remapServiceFields[T any](fields map[string]T, sources []int) map[string]T {
+	if fields == nil {
+		return nil
+	}
+
+	copies := map[int][]int{}
+	for j, i := range sources {
+		copies[i] = append(copies[i], j)
+	}
+
+	remapped := make(map[string]T, len(fields))
+	for key, value := range fields {
+		rest, ok := strings.CutPrefix(key, "services.")
+		if !ok {
+			remapped[key] = value
+			continue
+		}
+		index, suffix, _ := strings.Cut(rest, ".")
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			remapped[key] = value
+			continue
+		}
+		for _, j := range copies[i] {
+			newKey := joinField("services", strconv.Itoa(j))
+			if suffix != "" {
+				newKey += "." + suffix
+			}
+			remapped[newKey] = value
+		}
+	}
+	return remapped
+}
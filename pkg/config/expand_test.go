@@ -0,0 +1,117 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandServices(t *testing.T) {
+	tests := []struct {
+		name     string
+		services string
+		want     []interface{}
+		copies   map[string][]string
+		err      string
+	}{
+		{
+			name:     "count suffixes IDs",
+			services: `[{"type": "compute.instance", "instance_id": "web", "count": 2, "metadata": {"n": "${count.index}", "label": "web ${count.index}"}}]`,
+			want: []interface{}{
+				map[string]interface{}{"type": "compute.instance", "instance_id": "web-0", "metadata": map[string]interface{}{"n": 0.0, "label": "web 0"}},
+				map[string]interface{}{"type": "compute.instance", "instance_id": "web-1", "metadata": map[string]interface{}{"n": 1.0, "label": "web 1"}},
+			},
+			copies: map[string][]string{"web": {"web-0", "web-1"}},
+		},
+		{
+			name:     "IDs using the key are kept",
+			services: `[{"type": "storage.object", "bucket_id": "logs-${each.key}", "for_each": ["eu", "us"]}]`,
+			want: []interface{}{
+				map[string]interface{}{"type": "storage.object", "bucket_id": "logs-eu"},
+				map[string]interface{}{"type": "storage.object", "bucket_id": "logs-us"},
+			},
+		},
+		{
+			name: "for_each objects in key order",
+			services: `[
+  {"type": "compute.instance", "instance_id": "app", "size": "${each.value.size}", "for_each": {"b": {"size": "large"}, "a": {"size": "small"}}},
+  {"type": "storage.object", "bucket_id": "assets"}
+]`,
+			want: []interface{}{
+				map[string]interface{}{"type": "compute.instance", "instance_id": "app-a", "size": "small"},
+				map[string]interface{}{"type": "compute.instance", "instance_id": "app-b", "size": "large"},
+				map[string]interface{}{"type": "storage.object", "bucket_id": "assets"},
+			},
+			copies: map[string][]string{"app": {"app-a", "app-b"}},
+		},
+		{
+			name:     "count of zero",
+			services: `[{"type": "compute.instance", "instance_id": "web", "count": 0}]`,
+			want:     []interface{}{},
+		},
+		{
+			name:     "both count and for_each",
+			services: `[{"type": "compute.instance", "instance_id": "web", "count": 1, "for_each": ["a"]}]`,
+			err:      "'count' and 'for_each' cannot both be set",
+		},
+		{
+			name:     "fractional count",
+			services: `[{"type": "compute.instance", "instance_id": "web", "count": 1.5}]`,
+			err:      "'count' must be a non-negative integer",
+		},
+		{
+			name:     "duplicate keys",
+			services: `[{"type": "compute.instance", "instance_id": "web", "for_each": ["a", "a"]}]`,
+			err:      `duplicate 'for_each' key "a"`,
+		},
+		{
+			name:     "each outside for_each",
+			services: `[{"type": "compute.instance", "instance_id": "web-${each.key}"}]`,
+			err:      "${each.key} can only be used in a service with 'for_each'",
+		},
+		{
+			name:     "count.index with for_each",
+			services: `[{"type": "compute.instance", "instance_id": "web-${count.index}", "for_each": ["a"]}]`,
+			err:      "${count.index} requires 'count'",
+		},
+		{
+			name:     "missing value field",
+			services: `[{"type": "compute.instance", "instance_id": "web", "size": "${each.value.size}", "for_each": {"a": {"os": "ubuntu"}}}]`,
+			err:      `value of key "a" has no field "size"`,
+		},
+		{
+			name:     "embedded object",
+			services: `[{"type": "compute.instance", "instance_id": "web", "size": "x-${each.value}", "for_each": {"a": {"os": "ubuntu"}}}]`,
+			err:      "cannot embed ${each.value} in a string",
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		doc := loadTestDocument(t, dir, "config.json", `{"project_name": "demo", "services": `+tt.services+`}`)
+		if err := interpolate(doc); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		err := expandServices(doc)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			} else if !strings.HasPrefix(err.Error(), filepath.Join(dir, "config.json")+":1:") {
+				t.Errorf("%s: error %q has no position", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := doc.Data["services"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: services = %v, want %v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(doc.copies, tt.copies) {
+			t.Errorf("%s: copies = %v, want %v", tt.name, doc.copies, tt.copies)
+		}
+	}
+}
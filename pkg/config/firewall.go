@@ -0,0 +1,333 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FirewallRule allows traffic to or from a compute instance.
+type FirewallRule struct {
+	// Direction is ingress (default) or egress
+	Direction string `json:"direction,omitempty"`
+	// Protocol is tcp, udp, icmp or all
+	Protocol string `json:"protocol"`
+	// Ports are ports or ranges for tcp and udp; all ports when empty
+	Ports []PortRange `json:"ports,omitempty"`
+	// CIDRs are the sources of ingress and the destinations of egress
+	// traffic; anywhere when empty
+	CIDRs       []string `json:"cidrs,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// SSHAccess controls the SSH rule compute instances get by default.
+type SSHAccess struct {
+	Enabled     *bool    `json:"enabled,omitempty"`
+	SourceCIDRs []string `json:"source_cidrs,omitempty"`
+}
+
+// PortRange is a port or port range, written as 443 or "8000-8080".
+type PortRange string
+
+func (p *PortRange) UnmarshalJSON(data []byte) error {
+	var port int
+	if err := json.Unmarshal(data, &port); err == nil {
+		*p = PortRange(strconv.Itoa(port))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("port must be a number or a range like \"8000-8080\"")
+	}
+	*p = PortRange(s)
+	return nil
+}
+
+// bounds parses the range.
+func (p PortRange) bounds() (int, int, error) {
+	from, to, isRange := strings.Cut(string(p), "-")
+	if !isRange {
+		to = from
+	}
+	fromPort, err1 := strconv.Atoi(strings.TrimSpace(from))
+	toPort, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("port %q is not a port or range like \"8000-8080\"", string(p))
+	}
+	if fromPort < 1 || toPort > 65535 || fromPort > toPort {
+		return 0, 0, fmt.Errorf("port range %q must be within 1-65535 and ascending", string(p))
+	}
+	return fromPort, toPort, nil
+}
+
+// anywhere is the CIDR used when a rule or the SSH access names none.
+const anywhere = "0.0.0.0/0"
+
+// maxFirewallRules keeps the GCP rule names "<instance_id>-fw<n>" the module
+// creates within the 63 characters the instance naming rule leaves room for.
+const maxFirewallRules = 100
+
+// firewallRule is a rule with one port range, as the modules apply it.
+// Ports are 0 for all ports.
+type firewallRule struct {
+	Description      string
+	Egress           bool
+	Deny             bool
+	Protocol         string
+	FromPort, ToPort int
+	CIDRs            []string
+}
+
+// checkCIDR accepts IPv4 networks; the networks of the modules have no IPv6.
+func checkCIDR(cidr string) error {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return fmt.Errorf("%q is not an IPv4 CIDR like \"203.0.113.0/24\"", cidr)
+	}
+	if !ip.Equal(network.IP) {
+		return fmt.Errorf("%q has host bits set (did you mean %q?)", cidr, network.String())
+	}
+	return nil
+}
+
+// validateFirewall checks the firewall settings of the i-th service.
+func validateFirewall(doc *document, i int, service Service) []string {
+	var problems []string
+	report := func(field string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err))
+	}
+
+	if service.SSH != nil {
+		if service.SSH.Enabled != nil && !*service.SSH.Enabled && len(service.SSH.SourceCIDRs) > 0 {
+			report("ssh.source_cidrs", fmt.Errorf("source_cidrs cannot be set when SSH is disabled"))
+		}
+		for j, cidr := range service.SSH.SourceCIDRs {
+			if err := checkCIDR(cidr); err != nil {
+				report(fmt.Sprintf("ssh.source_cidrs.%d", j), err)
+			}
+		}
+		// allowed_ports would open SSH to anywhere again
+		if len(service.SSH.SourceCIDRs) > 0 || (service.SSH.Enabled != nil && !*service.SSH.Enabled) {
+			for j, port := range service.AllowedPorts {
+				if port == 22 {
+					report(fmt.Sprintf("allowed_ports.%d", j), fmt.Errorf("port 22 is SSH, which is controlled by 'ssh' (remove it from allowed_ports)"))
+				}
+			}
+		}
+	}
+
+	for j, rule := range service.FirewallRules {
+		if len(rule.Ports) > 0 && rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			report(fmt.Sprintf("firewall_rules.%d.ports", j), fmt.Errorf("ports can only be set for tcp and udp"))
+		}
+		for k, port := range rule.Ports {
+			if _, _, err := port.bounds(); err != nil {
+				report(fmt.Sprintf("firewall_rules.%d.ports.%d", j, k), err)
+			}
+		}
+		for k, cidr := range rule.CIDRs {
+			if err := checkCIDR(cidr); err != nil {
+				report(fmt.Sprintf("firewall_rules.%d.cidrs.%d", j, k), err)
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		if n := len(firewallRules(service)); n > maxFirewallRules {
+			report("firewall_rules", fmt.Errorf("%d rules after expanding port ranges, at most %d are supported", n, maxFirewallRules))
+		}
+	}
+	return problems
+}
+
+// key identifies what a rule allows or denies; rules with the same key only
+// differ in their description.
+func (r firewallRule) key() string {
+	cidrs := append([]string(nil), r.CIDRs...)
+	sort.Strings(cidrs)
+	return fmt.Sprintf("%t/%t/%s/%d-%d/%s", r.Egress, r.Deny, r.Protocol, r.FromPort, r.ToPort, strings.Join(cidrs, ","))
+}
+
+// firewallRules expands the SSH access, allowed_ports and firewall_rules of
+// a validated service into rules with one port range each. When egress rules
+// are given, all other outbound traffic is denied by a final rule. Rules
+// repeating an earlier one, e.g. port 22 in allowed_ports next to the SSH
+// rule, are dropped: AWS rejects duplicate security group permissions.
+func firewallRules(service Service) []firewallRule {
+	var rules []firewallRule
+
+	if service.SSH == nil || service.SSH.Enabled == nil || *service.SSH.Enabled {
+		cidrs := []string{anywhere}
+		if service.SSH != nil && len(service.SSH.SourceCIDRs) > 0 {
+			cidrs = service.SSH.SourceCIDRs
+		}
+		rules = append(rules, firewallRule{Description: "SSH", Protocol: "tcp", FromPort: 22, ToPort: 22, CIDRs: cidrs})
+	}
+
+	for _, port := range service.AllowedPorts {
+		rules = append(rules, firewallRule{Description: fmt.Sprintf("Port %d", port), Protocol: "tcp", FromPort: port, ToPort: port, CIDRs: []string{anywhere}})
+	}
+
+	restrictEgress := false
+	for _, rule := range service.FirewallRules {
+		base := firewallRule{
+			Description: rule.Description,
+			Egress:      rule.Direction == "egress",
+			Protocol:    rule.Protocol,
+			CIDRs:       rule.CIDRs,
+		}
+		if len(base.CIDRs) == 0 {
+			base.CIDRs = []string{anywhere}
+		}
+		restrictEgress = restrictEgress || base.Egress
+
+		if len(rule.Ports) == 0 {
+			if base.Description == "" {
+				base.Description = rule.Protocol
+			}
+			rules = append(rules, base)
+			continue
+		}
+		for _, port := range rule.Ports {
+			expanded := base
+			expanded.FromPort, expanded.ToPort, _ = port.bounds()
+			if expanded.Description == "" {
+				expanded.Description = fmt.Sprintf("%s %s", rule.Protocol, port)
+			}
+			rules = append(rules, expanded)
+		}
+	}
+
+	if restrictEgress {
+		rules = append(rules, firewallRule{Description: "Deny other outbound traffic", Egress: true, Deny: true, Protocol: "all", CIDRs: []string{anywhere}})
+	}
+
+	seen := map[string]bool{}
+	unique := rules[:0]
+	for _, rule := range rules {
+		if key := rule.key(); !seen[key] {
+			seen[key] = true
+			unique = append(unique, rule)
+		}
+	}
+	return unique
+}
+
+// firewallValues translates the rules of a compute instance into the
+// firewall_rules input of the provider's module, which generator_config.json
+// reads with "source": "firewall".
+func firewallValues(provider string, service Service) map[string]interface{} {
+	rules := firewallRules(service)
+	var values []interface{}
+
+	switch provider {
+	case "aws":
+		// Security groups only allow; without egress rules all outbound
+		// traffic is allowed, and the deny rule is implied otherwise
+		hasEgress := false
+		for _, rule := range rules {
+			if rule.Deny {
+				continue
+			}
+			hasEgress = hasEgress || rule.Egress
+			protocol, from, to := rule.Protocol, rule.FromPort, rule.ToPort
+			switch {
+			case protocol == "all":
+				protocol, from, to = "-1", 0, 0
+			case protocol == "icmp":
+				from, to = -1, -1
+			case from == 0:
+				from, to = 0, 65535
+			}
+			values = append(values, map[string]interface{}{
+				"description": rule.Description,
+				"egress":      rule.Egress,
+				"protocol":    protocol,
+				"from_port":   from,
+				"to_port":     to,
+				"cidr_blocks": rule.CIDRs,
+			})
+		}
+		if !hasEgress {
+			values = append(values, map[string]interface{}{
+				"description": "All outbound traffic",
+				"egress":      true,
+				"protocol":    "-1",
+				"from_port":   0,
+				"to_port":     0,
+				"cidr_blocks": []string{anywhere},
+			})
+		}
+
+	case "azure":
+		priorities := map[bool]int{false: 1001, true: 1001}
+		for i, rule := range rules {
+			direction, access, protocol, ports := "Inbound", "Allow", strings.ToUpper(rule.Protocol[:1])+rule.Protocol[1:], "*"
+			if rule.Egress {
+				direction = "Outbound"
+			}
+			priority := priorities[rule.Egress]
+			priorities[rule.Egress]++
+			cidrs := rule.CIDRs
+			if rule.Deny {
+				// Denying the Internet service tag keeps Azure platform traffic working
+				access, priority, cidrs = "Deny", 4096, []string{"Internet"}
+			}
+			if rule.Protocol == "all" {
+				protocol = "*"
+			}
+			if rule.FromPort != 0 {
+				ports = fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+				if rule.FromPort == rule.ToPort {
+					ports = strconv.Itoa(rule.FromPort)
+				}
+			}
+			values = append(values, map[string]interface{}{
+				"name":        fmt.Sprintf("rule-%d", i),
+				"description": rule.Description,
+				"priority":    priority,
+				"direction":   direction,
+				"access":      access,
+				"protocol":    protocol,
+				"port_range":  ports,
+				"cidrs":       cidrs,
+			})
+		}
+
+	case "gcp":
+		for _, rule := range rules {
+			direction, action, priority := "INGRESS", "allow", 1000
+			if rule.Egress {
+				direction = "EGRESS"
+			}
+			if rule.Deny {
+				action, priority = "deny", 65534
+			}
+			ports := []string{}
+			if rule.FromPort != 0 {
+				port := fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+				if rule.FromPort == rule.ToPort {
+					port = strconv.Itoa(rule.FromPort)
+				}
+				ports = append(ports, port)
+			}
+			values = append(values, map[string]interface{}{
+				"description": rule.Description,
+				"direction":   direction,
+				"action":      action,
+				"priority":    priority,
+				"protocol":    rule.Protocol,
+				"ports":       ports,
+				"ranges":      rule.CIDRs,
+			})
+		}
+	}
+
+	if values == nil {
+		values = []interface{}{}
+	}
+	return map[string]interface{}{"firewall_rules": values}
+}
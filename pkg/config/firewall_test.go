@@ -0,0 +1,88 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFirewallRulesDropDuplicates(t *testing.T) {
+	service := Service{
+		AllowedPorts: []int{22, 80, 80},
+		FirewallRules: []FirewallRule{
+			{Protocol: "tcp", Ports: []PortRange{"80"}, Description: "HTTP again"},
+		},
+	}
+	var got []string
+	for _, rule := range firewallRules(service) {
+		got = append(got, rule.Description)
+	}
+	want := []string{"SSH", "Port 80"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rules = %v, want %v", got, want)
+	}
+
+	// Port 22 can't be opened to anywhere while SSH is restricted or disabled
+	disabled := false
+	doc := &document{Path: "config.json"}
+	for _, ssh := range []*SSHAccess{{SourceCIDRs: []string{"203.0.113.0/24"}}, {Enabled: &disabled}} {
+		service.SSH = ssh
+		problems := validateFirewall(doc, 0, service)
+		if len(problems) != 1 || !strings.Contains(problems[0], "port 22 is SSH") {
+			t.Errorf("ssh %+v: problems %q, want port 22 rejected", ssh, problems)
+		}
+	}
+}
+
+func TestFirewallValues(t *testing.T) {
+	disabled := false
+	web := Service{
+		SSH:          &SSHAccess{SourceCIDRs: []string{"203.0.113.0/24"}},
+		AllowedPorts: []int{443},
+	}
+	restricted := Service{
+		SSH: &SSHAccess{Enabled: &disabled},
+		FirewallRules: []FirewallRule{
+			{Protocol: "udp", Ports: []PortRange{"8000-8001"}, CIDRs: []string{"10.0.0.0/8"}},
+			{Direction: "egress", Protocol: "tcp", Ports: []PortRange{"443"}},
+			{Protocol: "icmp"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		service  Service
+		want     []interface{}
+	}{
+		{"aws allows all outbound traffic by default", "aws", web, []interface{}{
+			map[string]interface{}{"description": "SSH", "egress": false, "protocol": "tcp", "from_port": 22, "to_port": 22, "cidr_blocks": []string{"203.0.113.0/24"}},
+			map[string]interface{}{"description": "Port 443", "egress": false, "protocol": "tcp", "from_port": 443, "to_port": 443, "cidr_blocks": []string{anywhere}},
+			map[string]interface{}{"description": "All outbound traffic", "egress": true, "protocol": "-1", "from_port": 0, "to_port": 0, "cidr_blocks": []string{anywhere}},
+		}},
+		{"aws implies the egress deny", "aws", restricted, []interface{}{
+			map[string]interface{}{"description": "udp 8000-8001", "egress": false, "protocol": "udp", "from_port": 8000, "to_port": 8001, "cidr_blocks": []string{"10.0.0.0/8"}},
+			map[string]interface{}{"description": "tcp 443", "egress": true, "protocol": "tcp", "from_port": 443, "to_port": 443, "cidr_blocks": []string{anywhere}},
+			map[string]interface{}{"description": "icmp", "egress": false, "protocol": "icmp", "from_port": -1, "to_port": -1, "cidr_blocks": []string{anywhere}},
+		}},
+		{"azure numbers priorities per direction", "azure", restricted, []interface{}{
+			map[string]interface{}{"name": "rule-0", "description": "udp 8000-8001", "priority": 1001, "direction": "Inbound", "access": "Allow", "protocol": "Udp", "port_range": "8000-8001", "cidrs": []string{"10.0.0.0/8"}},
+			map[string]interface{}{"name": "rule-1", "description": "tcp 443", "priority": 1001, "direction": "Outbound", "access": "Allow", "protocol": "Tcp", "port_range": "443", "cidrs": []string{anywhere}},
+			map[string]interface{}{"name": "rule-2", "description": "icmp", "priority": 1002, "direction": "Inbound", "access": "Allow", "protocol": "Icmp", "port_range": "*", "cidrs": []string{anywhere}},
+			map[string]interface{}{"name": "rule-3", "description": "Deny other outbound traffic", "priority": 4096, "direction": "Outbound", "access": "Deny", "protocol": "*", "port_range": "*", "cidrs": []string{"Internet"}},
+		}},
+		{"gcp denies other egress at low priority", "gcp", restricted, []interface{}{
+			map[string]interface{}{"description": "udp 8000-8001", "direction": "INGRESS", "action": "allow", "priority": 1000, "protocol": "udp", "ports": []string{"8000-8001"}, "ranges": []string{"10.0.0.0/8"}},
+			map[string]interface{}{"description": "tcp 443", "direction": "EGRESS", "action": "allow", "priority": 1000, "protocol": "tcp", "ports": []string{"443"}, "ranges": []string{anywhere}},
+			map[string]interface{}{"description": "icmp", "direction": "INGRESS", "action": "allow", "priority": 1000, "protocol": "icmp", "ports": []string{}, "ranges": []string{anywhere}},
+			map[string]interface{}{"description": "Deny other outbound traffic", "direction": "EGRESS", "action": "deny", "priority": 65534, "protocol": "all", "ports": []string{}, "ranges": []string{anywhere}},
+		}},
+		{"no rules", "gcp", Service{SSH: &SSHAccess{Enabled: &disabled}}, []interface{}{}},
+	}
+	for _, tt := range tests {
+		got := firewallValues(tt.provider, tt.service)["firewall_rules"]
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
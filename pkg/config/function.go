@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"multicloud-iac-provisioner/pkg/bundle"
+)
+
+// entryPointRules are the entry points each provider accepts: a Lambda
+// handler "<file>.<function>", a GCP function name or the name of the HTTP
+// function of an Azure function app.
+var entryPointRules = map[string]struct {
+	re      *regexp.Regexp
+	example string
+}{
+	"aws":   {regexp.MustCompile(`^[A-Za-z0-9_./-]*[A-Za-z0-9_-]\.[A-Za-z_$][A-Za-z0-9_$]*$`), `"<file>.<function>", e.g. "main.handler"`},
+	"gcp":   {regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`), `the function name, e.g. "handler"`},
+	"azure": {regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,127}$`), `the name of the HTTP function, e.g. "hello"`},
+}
+
+var environmentKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnvironment are the environment variables the function platforms
+// set themselves. Names ending in '_' are prefixes.
+var reservedEnvironment = map[string][]string{
+	"aws":   {"AWS_", "LAMBDA_", "_HANDLER"},
+	"gcp":   {"X_GOOGLE_", "K_", "PORT", "FUNCTION_TARGET", "FUNCTION_SIGNATURE_TYPE"},
+	"azure": {"FUNCTIONS_", "WEBSITE_", "APPINSIGHTS_", "SCM_", "AzureWebJobsStorage"},
+}
+
+// functionSource resolves the source_dir of a compute.function service
+// relative to the config file.
+func functionSource(doc *document, service Service) (string, error) {
+	path, err := resolvePath(filepath.Dir(doc.Path), service.SourceDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
+// validateFunction checks the source directory, entry point and settings of
+// the i-th service, a compute.function service on provider.
+func validateFunction(doc *document, i int, provider string, service Service) []string {
+	var problems []string
+	report := func(field string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err))
+	}
+
+	// Packaging is checked now so a bad directory fails before anything is
+	// provisioned; the archive itself is built at provision time
+	if dir, err := functionSource(doc, service); err != nil {
+		report("source_dir", err)
+	} else if files, err := bundle.Files(dir); err != nil {
+		report("source_dir", fmt.Errorf("cannot package %s: %w", dir, err))
+	} else if len(files) == 0 {
+		report("source_dir", fmt.Errorf("%s has no files to package", dir))
+	}
+
+	if rule, ok := entryPointRules[provider]; ok && !rule.re.MatchString(service.EntryPoint) {
+		report("entry_point", fmt.Errorf("invalid entry point %q (on %s it is %s)", service.EntryPoint, provider, rule.example))
+	}
+
+	if provider == "azure" && service.MemoryMB != 0 {
+		report("memory_mb", fmt.Errorf("Azure Consumption plan functions have a fixed memory size (remove memory_mb)"))
+	}
+
+	keys := make([]string, 0, len(service.Environment))
+	for key := range service.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !environmentKeyRe.MatchString(key) {
+			report("environment", fmt.Errorf("environment variable %q must consist of letters, digits and '_', not starting with a digit", key))
+			continue
+		}
+		for _, reserved := range reservedEnvironment[provider] {
+			if key == reserved || strings.HasSuffix(reserved, "_") && strings.HasPrefix(key, reserved) {
+				report("environment", fmt.Errorf("environment variable %q is reserved on %s", key, provider))
+				break
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	amiIDRe = regexp.MustCompile(`^ami-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	// Self-links may omit the scheme and host; "<project>/<image or family>"
+	// is the short form the google provider accepts
+	gcpImageLinkRe  = regexp.MustCompile(`^(https://www\.googleapis\.com/compute/v1/)?projects/[a-z][a-z0-9-]{4,28}[a-z0-9]/global/images/(family/)?[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	gcpImageShortRe = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]/[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	azureURNPartRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	azureVersionRe  = regexp.MustCompile(`^(latest|[0-9]+\.[0-9]+\.[0-9]+)$`)
+)
+
+// parseImage validates a provider image reference and converts it into the
+// value the provider's compute module takes as image.
+func parseImage(provider, ref string) (interface{}, error) {
+	switch provider {
+	case "aws":
+		if !amiIDRe.MatchString(ref) {
+			return nil, fmt.Errorf("image %q is not an AMI ID (ami- followed by 8 or 17 hex digits)", ref)
+		}
+		return map[string]interface{}{"id": ref}, nil
+	case "gcp":
+		if !gcpImageLinkRe.MatchString(ref) && !gcpImageShortRe.MatchString(ref) {
+			return nil, fmt.Errorf("image %q is not a GCP image self-link (projects/<project>/global/images/[family/]<name>) or <project>/<image or family>", ref)
+		}
+		return ref, nil
+	case "azure":
+		parts := strings.Split(ref, ":")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("image %q is not an Azure image URN (publisher:offer:sku:version)", ref)
+		}
+		for i, name := range []string{"publisher", "offer", "sku"} {
+			if !azureURNPartRe.MatchString(parts[i]) {
+				return nil, fmt.Errorf("image %q has an invalid %s %q", ref, name, parts[i])
+			}
+		}
+		if !azureVersionRe.MatchString(parts[3]) {
+			return nil, fmt.Errorf("image %q has an invalid version %q (expected latest or major.minor.patch)", ref, parts[3])
+		}
+		return map[string]interface{}{
+			"publisher": parts[0],
+			"offer":     parts[1],
+			"sku":       parts[2],
+			"version":   parts[3],
+		}, nil
+	}
+	return nil, fmt.Errorf("custom images are not supported on %s", provider)
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		provider, ref string
+		want          interface{}
+		err           string
+	}{
+		{provider: "aws", ref: "ami-0123456789abcdef0", want: map[string]interface{}{"id": "ami-0123456789abcdef0"}},
+		{provider: "aws", ref: "ami-01234567", want: map[string]interface{}{"id": "ami-01234567"}},
+		{provider: "aws", ref: "ami-0123", err: "is not an AMI ID"},
+		{provider: "aws", ref: "ubuntu", err: "is not an AMI ID"},
+		{provider: "gcp", ref: "projects/my-project/global/images/family/web", want: "projects/my-project/global/images/family/web"},
+		{provider: "gcp", ref: "https://www.googleapis.com/compute/v1/projects/my-project/global/images/web-v2", want: "https://www.googleapis.com/compute/v1/projects/my-project/global/images/web-v2"},
+		{provider: "gcp", ref: "debian-cloud/debian-12", want: "debian-cloud/debian-12"},
+		{provider: "gcp", ref: "projects/My-Project/global/images/web", err: "is not a GCP image self-link"},
+		{provider: "azure", ref: "Canonical:ubuntu-24_04-lts:server:latest", want: map[string]interface{}{
+			"publisher": "Canonical", "offer": "ubuntu-24_04-lts", "sku": "server", "version": "latest",
+		}},
+		{provider: "azure", ref: "Debian:debian-12:12:0.20240211.1648", want: map[string]interface{}{
+			"publisher": "Debian", "offer": "debian-12", "sku": "12", "version": "0.20240211.1648",
+		}},
+		{provider: "azure", ref: "Canonical:ubuntu:server", err: "is not an Azure image URN"},
+		{provider: "azure", ref: "Canonical:ubuntu:server:v1", err: `has an invalid version "v1"`},
+		{provider: "azure", ref: "Canonical:-ubuntu:server:latest", err: `has an invalid offer "-ubuntu"`},
+		{provider: "oracle", ref: "x", err: "custom images are not supported on oracle"},
+	}
+	for _, tt := range tests {
+		got, err := parseImage(tt.provider, tt.ref)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseImage(%s, %q): error %v, want %q", tt.provider, tt.ref, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseImage(%s, %q) = %v, %v, want %v", tt.provider, tt.ref, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// findExprs locates the "${...}" expressions and "$${" escapes for a literal
+// "${" in s, in the layout of regexp.FindAllStringSubmatchIndex: the match,
+// then the expression inside the braces, which is -1, -1 for escapes. A '}'
+// inside a quoted string, as in ${file("a}b")}, does not end the expression.
+// An unterminated "${" is left as text.
+func findExprs(s string) [][]int {
+	var matches [][]int
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			matches = append(matches, []int{i, i + 3, -1, -1})
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			end := exprEnd(s, i+2)
+			if end < 0 {
+				return matches
+			}
+			matches = append(matches, []int{i, end + 1, i + 2, end})
+			i = end
+		}
+	}
+	return matches
+}
+
+// exprEnd returns the index of the '}' closing the expression starting at
+// start, or -1.
+func exprEnd(s string, start int) int {
+	quoted := false
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	varRefRe  = regexp.MustCompile(`^var\.([A-Za-z_][A-Za-z0-9_-]*)$`)
+	envRefRe  = regexp.MustCompile(`^env\.([A-Za-z_][A-Za-z0-9_]*)$`)
+	fileRefRe = regexp.MustCompile(`^file\(\s*"([^"]*)"\s*\)$`)
+)
+
+// secretMask replaces secret values in rendered configs.
+const secretMask = "(secret)"
+
+type variable struct {
+	Value  interface{}
+	Secret bool
+}
+
+type interpolator struct {
+	doc     *document
+	baseDir string
+	// vars is nil while the variables block itself is evaluated
+	vars map[string]variable
+}
+
+// interpolate evaluates the variables block and all ${var.x}, ${env.X} and
+// ${file("path")} expressions in the document. Fields whose value derives
+// from a secret variable are recorded in doc.secrets, the variables are kept
+// in doc.vars for user data templates.
+func interpolate(doc *document) error {
+	ip := &interpolator{doc: doc, baseDir: filepath.Dir(doc.Path)}
+
+	vars, err := ip.declaredVariables()
+	if err != nil {
+		return err
+	}
+	delete(doc.Data, "variables")
+	ip.vars = vars
+	doc.vars = vars
+
+	value, _, err := ip.value(doc.Data, rootField)
+	if err != nil {
+		return err
+	}
+	doc.Data = value.(map[string]interface{})
+	return nil
+}
+
+// declaredVariables reads the variables block. A variable is either a plain
+// value or an object of the form {"value": ..., "secret": true}.
+func (ip *interpolator) declaredVariables() (map[string]variable, error) {
+	vars := map[string]variable{}
+	raw, ok := ip.doc.Data["variables"]
+	if !ok {
+		return vars, nil
+	}
+	block, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: 'variables' must be an object", ip.doc.location("variables"))
+	}
+
+	for name, decl := range block {
+		field := joinField("variables", name)
+		v := variable{Value: decl}
+
+		if obj, ok := decl.(map[string]interface{}); ok && isVariableDeclaration(obj) {
+			v.Value = obj["value"]
+			field = joinField(field, "value")
+			if secret, ok := obj["secret"].(bool); ok {
+				v.Secret = secret
+			}
+		}
+
+		value, secret, err := ip.value(v.Value, field)
+		if err != nil {
+			return nil, err
+		}
+		v.Value = value
+		v.Secret = v.Secret || secret
+		vars[name] = v
+	}
+	return vars, nil
+}
+
+func isVariableDeclaration(obj map[string]interface{}) bool {
+	if _, ok := obj["value"]; !ok {
+		return false
+	}
+	for key := range obj {
+		if key != "value" && key != "secret" && key != "description" {
+			return false
+		}
+	}
+	return true
+}
+
+func (ip *interpolator) value(v interface{}, field string) (interface{}, bool, error) {
+	switch val := v.(type) {
+	case string:
+		result, secret, err := ip.str(val, field)
+		if secret {
+			ip.doc.markSecret(field)
+		}
+		return result, secret, err
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		anySecret := false
+		for _, k := range keys {
+			child, secret, err := ip.value(val[k], joinField(field, k))
+			if err != nil {
+				return nil, false, err
+			}
+			val[k] = child
+			anySecret = anySecret || secret
+		}
+		return val, anySecret, nil
+	case []interface{}:
+		anySecret := false
+		for i, item := range val {
+			child, secret, err := ip.value(item, joinField(field, strconv.Itoa(i)))
+			if err != nil {
+				return nil, false, err
+			}
+			val[i] = child
+			anySecret = anySecret || secret
+		}
+		return val, anySecret, nil
+	}
+	return v, false, nil
+}
+
+// str evaluates the expressions in s. A string consisting of exactly one
+// expression takes the type of its value; otherwise values are embedded.
+func (ip *interpolator) str(s string, field string) (interface{}, bool, error) {
+	matches := findExprs(s)
+	if len(matches) == 0 {
+		return s, false, nil
+	}
+
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) && matches[0][2] >= 0 {
+		return ip.eval(s[matches[0][2]:matches[0][3]], field)
+	}
+
+	var b strings.Builder
+	anySecret := false
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+
+		if m[2] < 0 {
+			b.WriteString("${")
+			continue
+		}
+
+		value, secret, err := ip.eval(s[m[2]:m[3]], field)
+		if err != nil {
+			return nil, false, err
+		}
+		anySecret = anySecret || secret
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, false, fmt.Errorf("%s: cannot embed ${%s} in a string: value is not a string, number or bool", ip.doc.location(field), strings.TrimSpace(s[m[2]:m[3]]))
+		case string:
+			b.WriteString(value.(string))
+		default:
+			encoded, _ := json.Marshal(value)
+			b.Write(encoded)
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String(), anySecret, nil
+}
+
+func (ip *interpolator) eval(expr string, field string) (interface{}, bool, error) {
+	expr = strings.TrimSpace(expr)
+	location := ip.doc.location(field)
+
+	if m := varRefRe.FindStringSubmatch(expr); m != nil {
+		if ip.vars == nil {
+			return nil, false, fmt.Errorf("%s: variables cannot reference other variables (${%s})", location, expr)
+		}
+		v, ok := ip.vars[m[1]]
+		if !ok {
+			return nil, false, fmt.Errorf("%s: undefined variable %q", location, m[1])
+		}
+		return deepCopy(v.Value), v.Secret, nil
+	}
+
+	// Iteration references are evaluated when services are expanded
+	if iterRefRe.MatchString(expr) && strings.HasPrefix(field, "services.") {
+		return "${" + expr + "}", false, nil
+	}
+
+	if m := envRefRe.FindStringSubmatch(expr); m != nil {
+		value, ok := os.LookupEnv(m[1])
+		if !ok {
+			return nil, false, fmt.Errorf("%s: environment variable %s is not set", location, m[1])
+		}
+		return value, false, nil
+	}
+
+	if m := fileRefRe.FindStringSubmatch(expr); m != nil {
+		path, err := resolvePath(ip.baseDir, m[1])
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", location, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: error reading file: %v", location, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), false, nil
+	}
+
+	return nil, false, fmt.Errorf("%s: unsupported expression ${%s} (expected var.<name>, env.<NAME> or file(\"<path>\"))", location, expr)
+}
+
+// resolvePath expands "~" and resolves relative paths against baseDir, the
+// directory of the config file.
+func resolvePath(baseDir, path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error resolving home directory: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return path, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			copied[k] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(val))
+		for i, item := range val {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return v
+}
+
+func (d *document) markSecret(field string) {
+	if d.secrets == nil {
+		d.secrets = map[string]bool{}
+	}
+	d.secrets[field] = true
+}
+
+// hasSecret reports whether field or any value below it is secret.
+func (d *document) hasSecret(field string) bool {
+	for secret := range d.secrets {
+		if secret == field || strings.HasPrefix(secret, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// masked returns a copy of the document data with secret values replaced.
+func (d *document) masked() interface{} {
+	return d.maskValue(deepCopy(d.Data), rootField)
+}
+
+func (d *document) maskValue(v interface{}, field string) interface{} {
+	if d.secrets[field] {
+		return secretMask
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = d.maskValue(item, joinField(field, k))
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = d.maskValue(item, joinField(field, strconv.Itoa(i)))
+		}
+	}
+	return v
+}
+
+// hasConfigSecret reports whether a secret is used outside the services list;
+// such values can end up in the tfvars of every resource.
+func (d *document) hasConfigSecret() bool {
+	for secret := range d.secrets {
+		if secret != "services" && !strings.HasPrefix(secret, "services.") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindExprs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"plain", nil},
+		{"${var.a}", []string{"var.a"}},
+		{"${var.a}-${env.B}", []string{"var.a", "env.B"}},
+		{"$${var.a} ${var.b}", []string{"$${", "var.b"}},
+		{`${file("a}b.txt")}`, []string{`file("a}b.txt")`}},
+		{`${file("a\"}b")}!`, []string{`file("a\"}b")`}},
+		{"${var.a", nil},
+		{`${file("a}")`, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range findExprs(tt.s) {
+			if m[2] < 0 {
+				got = append(got, tt.s[m[0]:m[1]])
+			} else {
+				got = append(got, tt.s[m[2]:m[3]])
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findExprs(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateFileWithBrace(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key}1.pub"), []byte("ssh-ed25519 AAAA\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.json")
+	config := `{"project_name": "demo", "note": "key: ${file(\"key}1.pub\")}"}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := loadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := interpolate(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Data["note"]; got != "key: ssh-ed25519 AAAA" {
+		t.Errorf("note = %q, want %q", got, "key: ssh-ed25519 AAAA")
+	}
+}
@@ -0,0 +1,371 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Listener is a port a network.load_balancer accepts traffic on and the port
+// of the backends it forwards to (default: the same port).
+type Listener struct {
+	Port       int    `json:"port"`
+	Protocol   string `json:"protocol"` // tcp, http or https
+	TargetPort int    `json:"target_port,omitempty"`
+}
+
+// HealthCheck decides which backends of a network.load_balancer get traffic.
+type HealthCheck struct {
+	Protocol        string `json:"protocol,omitempty"` // tcp or http; http for http(s) listeners by default
+	Path            string `json:"path,omitempty"`     // http only, default "/"
+	Port            int    `json:"port,omitempty"`     // default: the target port of the first listener
+	IntervalSeconds int    `json:"interval_seconds,omitempty"`
+}
+
+// LoadBalancerTLS holds the certificate of https listeners: an ACM
+// certificate on AWS, or the domains of a Google-managed certificate on GCP.
+type LoadBalancerTLS struct {
+	Certificate string   `json:"certificate,omitempty"`
+	Domains     []string `json:"domains,omitempty"`
+}
+
+var acmCertificateRe = regexp.MustCompile(`^arn:aws[a-z-]*:acm:[a-z0-9-]+:[0-9]{12}:certificate/[A-Za-z0-9-]+$`)
+
+// defaultHealthCheckInterval is the interval between health checks in seconds.
+const defaultHealthCheckInterval = 30
+
+// maxPassthroughPorts is the number of ports a GCP passthrough forwarding
+// rule lists.
+const maxPassthroughPorts = 5
+
+// targetPort returns the port of the backends a listener forwards to.
+func (l Listener) targetPort() int {
+	if l.TargetPort != 0 {
+		return l.TargetPort
+	}
+	return l.Port
+}
+
+// layer7 reports whether a load balancer terminates HTTP, i.e. whether any
+// of its listeners is http or https.
+func layer7(service Service) bool {
+	for _, listener := range service.Listeners {
+		if listener.Protocol != "tcp" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateLoadBalancer checks the listeners, health check and TLS settings of
+// the i-th service, a network.load_balancer service on provider.
+func validateLoadBalancer(doc *document, i int, provider string, service Service) []string {
+	var problems []string
+	report := func(field string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err))
+	}
+
+	backends := map[string]bool{}
+	for j, backend := range service.Backends {
+		if backends[backend] {
+			report(fmt.Sprintf("backends.%d", j), fmt.Errorf("backend %q is listed twice", backend))
+		}
+		backends[backend] = true
+	}
+
+	ports := map[int]bool{}
+	targetPorts := map[int]bool{}
+	https, tcp := false, false
+	for j, listener := range service.Listeners {
+		field := fmt.Sprintf("listeners.%d", j)
+		if ports[listener.Port] {
+			report(field+".port", fmt.Errorf("port %d has two listeners", listener.Port))
+		}
+		ports[listener.Port] = true
+		https = https || listener.Protocol == "https"
+		tcp = tcp || listener.Protocol == "tcp"
+
+		switch provider {
+		case "azure":
+			if listener.Protocol == "https" {
+				report(field+".protocol", fmt.Errorf("Azure load balancers forward TCP; terminate TLS on the backends and use a tcp listener"))
+			}
+			// Rules sharing a backend port would need floating IPs
+			if targetPorts[listener.targetPort()] {
+				report(field+".target_port", fmt.Errorf("target port %d is used by another listener", listener.targetPort()))
+			}
+		case "gcp":
+			if listener.Protocol == "tcp" && listener.targetPort() != listener.Port {
+				report(field+".target_port", fmt.Errorf("GCP passthrough load balancers forward to the listener port; target_port must be %d", listener.Port))
+			}
+		}
+		targetPorts[listener.targetPort()] = true
+	}
+	if tcp && layer7(service) && provider != "azure" {
+		report("listeners", fmt.Errorf("tcp listeners cannot be combined with http or https listeners on %s (use two load balancers)", provider))
+	}
+	if provider == "gcp" && tcp && len(service.Listeners) > maxPassthroughPorts {
+		report("listeners", fmt.Errorf("GCP passthrough load balancers have at most %d listeners", maxPassthroughPorts))
+	}
+
+	if check := service.HealthCheck; check != nil {
+		protocol := check.Protocol
+		if protocol == "" && layer7(service) {
+			protocol = "http"
+		}
+		if check.Path != "" && protocol != "http" {
+			report("health_check.path", fmt.Errorf("path can only be set for http health checks"))
+		} else if check.Path != "" && !strings.HasPrefix(check.Path, "/") {
+			report("health_check.path", fmt.Errorf("path %q must start with '/'", check.Path))
+		}
+		if provider == "aws" && layer7(service) && protocol == "tcp" {
+			report("health_check.protocol", fmt.Errorf("AWS application load balancers check their backends over http"))
+		}
+	}
+
+	tls := service.TLS
+	switch {
+	case https && tls == nil:
+		report("tls", fmt.Errorf("https listeners need 'tls'"))
+	case !https && tls != nil:
+		report("tls", fmt.Errorf("tls can only be set with https listeners"))
+	case tls != nil && provider == "aws":
+		if len(tls.Domains) > 0 {
+			report("tls.domains", fmt.Errorf("AWS load balancers use an ACM certificate; set tls.certificate instead"))
+		}
+		if !acmCertificateRe.MatchString(tls.Certificate) {
+			report("tls.certificate", fmt.Errorf("%q is not an ACM certificate ARN like \"arn:aws:acm:us-east-1:123456789012:certificate/...\"", tls.Certificate))
+		}
+	case tls != nil && provider == "gcp":
+		if tls.Certificate != "" {
+			report("tls.certificate", fmt.Errorf("GCP load balancers get a Google-managed certificate; set tls.domains instead"))
+		}
+		if len(tls.Domains) == 0 {
+			report("tls.domains", fmt.Errorf("the Google-managed certificate needs at least one domain"))
+		}
+		for j, domain := range tls.Domains {
+			if err := checkHostname(domain, 2, false, zoneLabelRe); err != nil {
+				report(fmt.Sprintf("tls.domains.%d", j), err)
+			}
+		}
+	}
+	return problems
+}
+
+// loadBalancerValues normalizes the listeners, health check and TLS settings
+// of a load balancer into the inputs of the provider's module, which
+// generator_config.json reads with "source": "load_balancer".
+func loadBalancerValues(provider string, service Service) map[string]interface{} {
+	var listeners []interface{}
+	for _, listener := range service.Listeners {
+		listeners = append(listeners, map[string]interface{}{
+			"port":        listener.Port,
+			"protocol":    listener.Protocol,
+			"target_port": listener.targetPort(),
+		})
+	}
+
+	check := HealthCheck{Protocol: "tcp", Path: "/", IntervalSeconds: defaultHealthCheckInterval}
+	if layer7(service) {
+		check.Protocol = "http"
+	}
+	if len(service.Listeners) > 0 {
+		check.Port = service.Listeners[0].targetPort()
+	}
+	if configured := service.HealthCheck; configured != nil {
+		if configured.Protocol != "" {
+			check.Protocol = configured.Protocol
+		}
+		if configured.Path != "" {
+			check.Path = configured.Path
+		}
+		if configured.Port != 0 {
+			check.Port = configured.Port
+		}
+		if configured.IntervalSeconds != 0 {
+			check.IntervalSeconds = configured.IntervalSeconds
+		}
+	}
+
+	values := map[string]interface{}{
+		"listeners": listeners,
+		"health_check": map[string]interface{}{
+			"protocol":         check.Protocol,
+			"path":             check.Path,
+			"port":             check.Port,
+			"interval_seconds": check.IntervalSeconds,
+		},
+	}
+	if tls := service.TLS; tls != nil {
+		switch provider {
+		case "aws":
+			values["certificate_arn"] = tls.Certificate
+		case "gcp":
+			values["tls_domains"] = tls.Domains
+		}
+	}
+	return values
+}
+
+// joinedNetwork returns the network.vpc a compute instance joins, or "" for
+// its own or the default network.
+func joinedNetwork(res ResourcePlan) string {
+	for _, ref := range res.Inputs {
+		if ref.Output == "subnet_ids" {
+			return ref.Resource
+		}
+	}
+	return ""
+}
+
+// allowsTCP reports whether the firewall of a compute instance allows
+// inbound TCP traffic to port.
+func allowsTCP(service Service, port int) bool {
+	for _, rule := range firewallRules(service) {
+		if rule.Egress || rule.Deny || (rule.Protocol != "tcp" && rule.Protocol != "all") {
+			continue
+		}
+		if rule.FromPort == 0 || (rule.FromPort <= port && port <= rule.ToPort) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkLoadBalancers resolves the backends of network.load_balancer services
+// into module inputs and dependencies. copies maps the IDs of services using
+// count or for_each to the IDs of their copies, which all become backends;
+// copies across regions are used in the load balancer's region. Backends
+// must share one network and allow traffic on the target ports. subnets
+// holds the subnet names of every network by resource ID.
+func linkLoadBalancers(plan *ProvisioningPlan, services []Service, copies map[string][]string, subnets map[string][]string, locations []string) error {
+	zones := map[string]string{}
+	for _, res := range plan.Resources {
+		if res.Type == "network.vpc" {
+			zones[res.ID] = res.Zone
+		}
+	}
+
+	// GCP instances join one load-balanced instance group at most
+	balanced := map[string]string{}
+
+	var problems []string
+	for i, service := range services {
+		if service.Type != "network.load_balancer" {
+			continue
+		}
+		res := &plan.Resources[i]
+		report := func(format string, args ...interface{}) {
+			problems = append(problems, fmt.Sprintf("%s: %s", locations[i], fmt.Sprintf(format, args...)))
+		}
+
+		var backends []int
+		for _, name := range service.Backends {
+			names := copies[name]
+			if len(names) == 0 {
+				names = []string{name}
+			}
+			for _, name := range names {
+				found := -1
+				for j, candidate := range plan.Resources {
+					if candidate.ID == name || (found == -1 && candidate.ID == name+"-"+regionSuffix(res.Region)) {
+						found = j
+					}
+				}
+				if found == -1 {
+					report("unknown backend %q", name)
+					continue
+				}
+				backends = append(backends, found)
+			}
+		}
+		if len(backends) == 0 {
+			continue
+		}
+
+		network := "-"
+		var ids []string
+		ok := true
+		for _, j := range backends {
+			backend := plan.Resources[j]
+			switch {
+			case backend.Type != "compute.instance":
+				report("backend %q is a %s, not a compute.instance", backend.ID, backend.Type)
+			case backend.Provider != res.Provider:
+				report("backend %q is on %s, %s is on %s", backend.ID, backend.Provider, res.ID, res.Provider)
+			case backend.Region != res.Region:
+				report("backend %q is in %s, %s is in %s", backend.ID, backend.Region, res.ID, res.Region)
+			case network != "-" && joinedNetwork(backend) != network:
+				report("backends %q and %q are on different networks (put them on one network.vpc)", ids[0], backend.ID)
+			case res.Provider == "gcp" && balanced[backend.ID] != "":
+				report("backend %q is already behind %q; GCP instances can be behind one load balancer only", backend.ID, balanced[backend.ID])
+			default:
+				if res.Provider == "gcp" {
+					balanced[backend.ID] = res.ID
+				}
+				network = joinedNetwork(backend)
+				ids = append(ids, backend.ID)
+				continue
+			}
+			ok = false
+		}
+		if !ok {
+			continue
+		}
+
+		// GCP proxies reach the backends from the health check ranges the
+		// module opens; other load balancers forward to the target ports
+		if res.Provider != "gcp" || !layer7(service) {
+			for _, j := range backends {
+				var closed []string
+				for _, listener := range service.Listeners {
+					port := listener.targetPort()
+					if !allowsTCP(services[j], port) && !containsString(closed, fmt.Sprint(port)) {
+						closed = append(closed, fmt.Sprint(port))
+					}
+				}
+				if len(closed) > 0 {
+					report("backend %q does not allow TCP port %s (add it to allowed_ports or firewall_rules)", plan.Resources[j].ID, strings.Join(closed, ", "))
+				}
+			}
+		}
+
+		switch {
+		case res.Provider == "aws" && network == "":
+			report("AWS load balancers need their backends on a network.vpc (set 'network' on %s)", strings.Join(ids, ", "))
+			continue
+		case res.Provider == "aws" && layer7(service) && (len(subnets[network]) < 2 || zones[network] != ""):
+			report("application load balancers need subnets in two zones; give network %q two subnets and no zone", network)
+			continue
+		case res.Provider == "azure" && network == "" && len(ids) > 1:
+			report("Azure backends must share a virtual network (set 'network' on %s)", strings.Join(ids, ", "))
+			continue
+		}
+
+		res.DependsOn = append(res.DependsOn, ids...)
+		if network != "" {
+			res.DependsOn = append(res.DependsOn, network)
+		}
+		if res.Inputs == nil {
+			res.Inputs = map[string]OutputRef{}
+		}
+		switch res.Provider {
+		case "aws":
+			res.Inputs["instance_ids"] = OutputRef{Resources: ids, Output: "instance_id"}
+			res.Inputs["vpc_id"] = OutputRef{Resource: network, Output: "vpc_id"}
+			res.Inputs["subnet_ids"] = OutputRef{Resource: network, Output: "subnet_ids"}
+		case "azure":
+			res.Inputs["network_interface_ids"] = OutputRef{Resources: ids, Output: "network_interface_id"}
+		case "gcp":
+			res.Inputs["instances"] = OutputRef{Resources: ids, Output: "instance_self_link"}
+			if network != "" {
+				res.Inputs["network"] = OutputRef{Resource: network, Output: "network"}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLoadBalancer(t *testing.T) {
+	listeners := func(specs ...Listener) []Listener { return specs }
+	http := Listener{Port: 80, Protocol: "http"}
+	https := Listener{Port: 443, Protocol: "https", TargetPort: 80}
+	tcp := Listener{Port: 5432, Protocol: "tcp"}
+	certificate := "arn:aws:acm:eu-west-1:123456789012:certificate/0b1c2d3e"
+
+	tests := []struct {
+		name     string
+		provider string
+		service  Service
+		want     []string
+	}{
+		{"http", "aws", Service{Backends: []string{"web-1", "web-2"}, Listeners: listeners(http)}, nil},
+		{"https on AWS", "aws", Service{Listeners: listeners(http, https), TLS: &LoadBalancerTLS{Certificate: certificate}}, nil},
+		{"https on GCP", "gcp", Service{Listeners: listeners(https), TLS: &LoadBalancerTLS{Domains: []string{"www.example.com"}}}, nil},
+		{"tcp on Azure", "azure", Service{Listeners: listeners(tcp, Listener{Port: 80, Protocol: "tcp", TargetPort: 8080})}, nil},
+		{"backend listed twice", "aws", Service{Backends: []string{"web", "web"}, Listeners: listeners(http)}, []string{`backend "web" is listed twice`}},
+		{"two listeners on a port", "aws", Service{Listeners: listeners(http, Listener{Port: 80, Protocol: "http"})}, []string{"port 80 has two listeners"}},
+		{"tcp mixed with http", "gcp", Service{Listeners: listeners(http, tcp)}, []string{"tcp listeners cannot be combined with http or https listeners on gcp"}},
+		{"https on Azure", "azure", Service{Listeners: listeners(https), TLS: &LoadBalancerTLS{}}, []string{"Azure load balancers forward TCP"}},
+		{"shared target port on Azure", "azure", Service{Listeners: listeners(Listener{Port: 80, Protocol: "tcp"}, Listener{Port: 8080, Protocol: "tcp", TargetPort: 80})}, []string{"target port 80 is used by another listener"}},
+		{"tcp target port on GCP", "gcp", Service{Listeners: listeners(Listener{Port: 80, Protocol: "tcp", TargetPort: 8080})}, []string{"target_port must be 80"}},
+		{"too many GCP passthrough ports", "gcp", Service{Listeners: listeners(
+			Listener{Port: 1, Protocol: "tcp"}, Listener{Port: 2, Protocol: "tcp"}, Listener{Port: 3, Protocol: "tcp"},
+			Listener{Port: 4, Protocol: "tcp"}, Listener{Port: 5, Protocol: "tcp"}, Listener{Port: 6, Protocol: "tcp"},
+		)}, []string{"at most 5 listeners"}},
+		{"health check path on tcp", "azure", Service{Listeners: listeners(tcp), HealthCheck: &HealthCheck{Path: "/health"}}, []string{"path can only be set for http health checks"}},
+		{"relative health check path", "aws", Service{Listeners: listeners(http), HealthCheck: &HealthCheck{Path: "health"}}, []string{`path "health" must start with '/'`}},
+		{"tcp health check on an AWS ALB", "aws", Service{Listeners: listeners(http), HealthCheck: &HealthCheck{Protocol: "tcp"}}, []string{"AWS application load balancers check their backends over http"}},
+		{"https without tls", "aws", Service{Listeners: listeners(https)}, []string{"https listeners need 'tls'"}},
+		{"tls without https", "gcp", Service{Listeners: listeners(http), TLS: &LoadBalancerTLS{Domains: []string{"www.example.com"}}}, []string{"tls can only be set with https listeners"}},
+		{"AWS needs a certificate ARN", "aws", Service{Listeners: listeners(https), TLS: &LoadBalancerTLS{Domains: []string{"www.example.com"}}}, []string{
+			"set tls.certificate instead", `"" is not an ACM certificate ARN`,
+		}},
+		{"GCP manages the certificate", "gcp", Service{Listeners: listeners(https), TLS: &LoadBalancerTLS{Certificate: certificate}}, []string{
+			"set tls.domains instead", "the Google-managed certificate needs at least one domain",
+		}},
+		{"invalid certificate domain", "gcp", Service{Listeners: listeners(https), TLS: &LoadBalancerTLS{Domains: []string{"example"}}}, []string{`"example" is not a fully qualified domain name`}},
+	}
+	doc := &document{Path: "config.json"}
+	for _, tt := range tests {
+		problems := validateLoadBalancer(doc, 0, tt.provider, tt.service)
+		if len(problems) != len(tt.want) {
+			t.Errorf("%s: got %q, want %d problems", tt.name, problems, len(tt.want))
+			continue
+		}
+		for j, want := range tt.want {
+			if !strings.Contains(problems[j], want) {
+				t.Errorf("%s: problem %q does not contain %q", tt.name, problems[j], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ModuleOutput is an output declared by a module. Sensitive outputs must be
+// forwarded as sensitive too.
+type ModuleOutput struct {
+	Name      string
+	Sensitive bool
+}
+
+var sensitiveRe = regexp.MustCompile(`\bsensitive\s*=\s*true\b`)
+
+// ModuleOutputs scans the .tf files of a module for its outputs.
+func ModuleOutputs(modulePath string) ([]ModuleOutput, error) {
+	files, err := os.ReadDir(modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []ModuleOutput
+	// Regex to find: output "name" {
+	re := regexp.MustCompile(`output\s+\"([\w_-]+)\"\s+\{`)
+
+	for _, file := range files {
+		if filepath.Ext(file.Name()) != ".tf" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(modulePath, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		text := string(content)
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		for _, match := range matches {
+			// The block ends at the first closing brace at the start of a line
+			body := text[match[1]:]
+			if end := strings.Index(body, "\n}"); end >= 0 {
+				body = body[:end]
+			}
+			outputs = append(outputs, ModuleOutput{
+				Name:      text[match[2]:match[3]],
+				Sensitive: sensitiveRe.MatchString(body),
+			})
+		}
+	}
+	return outputs, nil
+}
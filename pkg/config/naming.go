@@ -0,0 +1,303 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// namingRule describes the names a provider accepts for a resource. Character
+// sets are regexp character class contents (e.g. "a-z0-9-").
+type namingRule struct {
+	// Field is the service field holding the name
+	Field string
+	// Kind names the cloud resource in messages
+	Kind     string
+	Min, Max int
+	// Chars are the allowed characters, described by CharsDesc
+	Chars     string
+	CharsDesc string
+	// First and Last restrict the first and last character
+	First, FirstDesc string
+	Last, LastDesc   string
+	// Reserved reports names the provider rejects despite matching the rules
+	Reserved func(name string) string
+	// Lowercased is set when the module lowercases the name, so only the
+	// lowercased name must match the rules
+	Lowercased bool
+}
+
+// namingRules is the catalog of provider naming rules by provider and service type.
+var namingRules = map[string]map[string][]namingRule{
+	"aws": {
+		"compute.function": {{
+			// The IAM role "<function_id>-role" must fit in 64 characters
+			Field: "function_id", Kind: "Lambda function name", Min: 1, Max: 59,
+			Chars: "a-zA-Z0-9_-", CharsDesc: "letters, digits, '_' and '-'",
+		}},
+		"container.cluster": {{
+			// The IAM roles "<cluster_id>-cluster" and "<cluster_id>-nodes"
+			// must fit in 64 characters
+			Field: "cluster_id", Kind: "EKS cluster name", Min: 1, Max: 56,
+			Chars: "a-zA-Z0-9_-", CharsDesc: "letters, digits, '_' and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+		}},
+		"database.sql": {{
+			Field: "database_id", Kind: "RDS instance identifier", Min: 1, Max: 63,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+			Reserved: func(name string) string {
+				if strings.Contains(name, "--") {
+					return "must not contain '--'"
+				}
+				return ""
+			},
+		}},
+		"network.load_balancer": {{
+			// Target groups are named "<load_balancer_id>-<port>" and must fit
+			// in 32 characters
+			Field: "load_balancer_id", Kind: "load balancer name", Min: 1, Max: 26,
+			Chars: "a-zA-Z0-9-", CharsDesc: "letters, digits and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+			Last: "a-zA-Z0-9", LastDesc: "a letter or digit",
+			Reserved: func(name string) string {
+				if strings.HasPrefix(strings.ToLower(name), "internal-") {
+					return "must not start with 'internal-'"
+				}
+				return ""
+			},
+		}},
+		"storage.object": {{
+			Field: "bucket_id", Kind: "S3 bucket name", Min: 3, Max: 63,
+			Chars: "a-z0-9.-", CharsDesc: "lowercase letters, digits, '.' and '-'",
+			First: "a-z0-9", FirstDesc: "a lowercase letter or digit",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+			Reserved:   reservedS3Name,
+			Lowercased: true,
+		}},
+	},
+	"gcp": {
+		"compute.function": {{
+			// Functions run as Cloud Run services of the same name, which
+			// allow 49 characters
+			Field: "function_id", Kind: "Cloud Functions name", Min: 1, Max: 49,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"container.cluster": {{
+			Field: "cluster_id", Kind: "GKE cluster name", Min: 1, Max: 40,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"compute.instance": {{
+			// The firewall rules "<instance_id>-fw<n>" must fit in 63 characters too
+			Field: "instance_id", Kind: "GCE instance name", Min: 1, Max: 58,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"database.sql": {{
+			// "<project_id>:<database_id>" must fit in 98 characters with
+			// project IDs of up to 30
+			Field: "database_id", Kind: "Cloud SQL instance name", Min: 1, Max: 67,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"network.load_balancer": {{
+			// Backend services and forwarding rules are named
+			// "<load_balancer_id>-<port>"
+			Field: "load_balancer_id", Kind: "load balancer name", Min: 1, Max: 57,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"network.vpc": {{
+			Field: "network_id", Kind: "VPC network name", Min: 1, Max: 63,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z", FirstDesc: "a lowercase letter",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"storage.object": {{
+			Field: "bucket_id", Kind: "GCS bucket name", Min: 3, Max: 63,
+			Chars: "a-z0-9._-", CharsDesc: "lowercase letters, digits, '.', '_' and '-'",
+			First: "a-z0-9", FirstDesc: "a lowercase letter or digit",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+			Reserved:   reservedGCSName,
+			Lowercased: true,
+		}},
+	},
+	"azure": {
+		"compute.function": {{
+			// The app name is also its host name <function_id>.azurewebsites.net
+			Field: "function_id", Kind: "Azure function app name", Min: 2, Max: 60,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z0-9", FirstDesc: "a lowercase letter or digit",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"container.cluster": {{
+			Field: "cluster_id", Kind: "AKS cluster name", Min: 1, Max: 63,
+			Chars: "a-zA-Z0-9_-", CharsDesc: "letters, digits, '_' and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+			Last: "a-zA-Z0-9", LastDesc: "a letter or digit",
+		}},
+		"compute.instance": {{
+			Field: "instance_id", Kind: "Azure VM name", Min: 1, Max: 64,
+			Chars: "a-zA-Z0-9._-", CharsDesc: "letters, digits, '.', '_' and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+			Last: "a-zA-Z0-9_", LastDesc: "a letter, digit or '_'",
+		}},
+		"database.sql": {{
+			Field: "database_id", Kind: "Azure database server name", Min: 3, Max: 63,
+			Chars: "a-z0-9-", CharsDesc: "lowercase letters, digits and '-'",
+			First: "a-z0-9", FirstDesc: "a lowercase letter or digit",
+			Last: "a-z0-9", LastDesc: "a lowercase letter or digit",
+		}},
+		"network.load_balancer": {{
+			Field: "load_balancer_id", Kind: "Azure load balancer name", Min: 1, Max: 80,
+			Chars: "a-zA-Z0-9._-", CharsDesc: "letters, digits, '.', '_' and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+			Last: "a-zA-Z0-9_", LastDesc: "a letter, digit or '_'",
+		}},
+		"network.vpc": {{
+			Field: "network_id", Kind: "Azure virtual network name", Min: 2, Max: 64,
+			Chars: "a-zA-Z0-9._-", CharsDesc: "letters, digits, '.', '_' and '-'",
+			First: "a-zA-Z0-9", FirstDesc: "a letter or digit",
+			Last: "a-zA-Z0-9_", LastDesc: "a letter, digit or '_'",
+		}},
+		"storage.object": {{
+			// The module strips other characters, which would hide collisions
+			Field: "bucket_id", Kind: "Azure storage account name", Min: 3, Max: 24,
+			Chars: "a-z0-9", CharsDesc: "lowercase letters and digits",
+		}},
+	},
+}
+
+func reservedS3Name(name string) string {
+	switch {
+	case strings.Contains(name, ".."):
+		return "must not contain '..'"
+	case net.ParseIP(name) != nil:
+		return "must not be formatted as an IP address"
+	case strings.HasPrefix(name, "xn--") || strings.HasPrefix(name, "sthree-"):
+		return "must not start with 'xn--' or 'sthree-'"
+	case strings.HasSuffix(name, "-s3alias") || strings.HasSuffix(name, "--ol-s3"):
+		return "must not end with '-s3alias' or '--ol-s3'"
+	}
+	return ""
+}
+
+func reservedGCSName(name string) string {
+	switch {
+	case strings.Contains(name, ".."):
+		return "must not contain '..'"
+	case net.ParseIP(name) != nil:
+		return "must not be formatted as an IP address"
+	case strings.HasPrefix(name, "goog"):
+		return "must not start with 'goog'"
+	case strings.Contains(name, "google"):
+		return "must not contain 'google'"
+	}
+	return ""
+}
+
+// check returns the rules name violates.
+func (r namingRule) check(name string) []string {
+	if r.Lowercased {
+		name = strings.ToLower(name)
+	}
+	var problems []string
+	if n := len(name); n < r.Min || n > r.Max {
+		problems = append(problems, fmt.Sprintf("must be %d-%d characters long (has %d)", r.Min, r.Max, n))
+	}
+	if name == "" {
+		return problems
+	}
+	if !regexp.MustCompile(`^[` + r.Chars + `]*$`).MatchString(name) {
+		problems = append(problems, "may only contain "+r.CharsDesc)
+	}
+	if r.First != "" && !regexp.MustCompile(`^[`+r.First+`]`).MatchString(name) {
+		problems = append(problems, "must start with "+r.FirstDesc)
+	}
+	if r.Last != "" && !regexp.MustCompile(`[`+r.Last+`]$`).MatchString(name) {
+		problems = append(problems, "must end with "+r.LastDesc)
+	}
+	if r.Reserved != nil {
+		if problem := r.Reserved(name); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// suggest derives a valid name from name, or returns "" if it cannot.
+func (r namingRule) suggest(name string) string {
+	if r.Lowercased || !strings.Contains(r.Chars, "A-Z") {
+		name = strings.ToLower(name)
+	}
+
+	// A literal '-' is the last character of the class
+	replacement := ""
+	if strings.HasSuffix(r.Chars, "-") {
+		replacement = "-"
+	}
+	name = regexp.MustCompile(`[^`+r.Chars+`]`).ReplaceAllString(name, replacement)
+	if replacement != "" {
+		name = regexp.MustCompile(`-{2,}`).ReplaceAllString(name, "-")
+	}
+
+	trimEdges := func(s string) string {
+		if r.First != "" {
+			s = regexp.MustCompile(`^[^`+r.First+`]+`).ReplaceAllString(s, "")
+		}
+		if r.Last != "" {
+			s = regexp.MustCompile(`[^`+r.Last+`]+$`).ReplaceAllString(s, "")
+		}
+		return s
+	}
+
+	name = trimEdges(name)
+	if len(name) > r.Max {
+		name = trimEdges(name[:r.Max])
+	}
+	for len(name) < r.Min {
+		name += "0"
+	}
+
+	if len(r.check(name)) > 0 {
+		return ""
+	}
+	return name
+}
+
+// validateNames checks the resource names of all services, including the
+// copies created by regions, against namingRules.
+func validateNames(doc *document, config Config) []string {
+	var problems []string
+	for i, configured := range config.Services {
+		rules := namingRules[config.serviceProvider(configured)][configured.Type]
+		for _, service := range expandRegions(configured) {
+			for _, rule := range rules {
+				name, field := service.resourceID()
+				if name == "" || field != rule.Field {
+					continue
+				}
+
+				issues := rule.check(name)
+				if len(issues) == 0 {
+					continue
+				}
+				msg := fmt.Sprintf("%s: %s %q %s", doc.location(fmt.Sprintf("services.%d.%s", i, rule.Field)), rule.Kind, name, strings.Join(issues, ", "))
+				if suggestion := rule.suggest(name); suggestion != "" {
+					msg += fmt.Sprintf(" (try %q)", suggestion)
+				}
+				problems = append(problems, msg)
+			}
+		}
+	}
+	return problems
+}
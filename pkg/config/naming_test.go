@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamingRuleCheck(t *testing.T) {
+	tests := []struct {
+		provider, serviceType, name string
+		want                        []string
+	}{
+		{"aws", "storage.object", "my-bucket", nil},
+		{"aws", "storage.object", "My-Bucket", nil},
+		{"aws", "storage.object", "my_bucket", []string{"may only contain lowercase letters, digits, '.' and '-'"}},
+		{"aws", "storage.object", "ab", []string{"must be 3-63 characters long (has 2)"}},
+		{"aws", "storage.object", "192.168.0.1", []string{"must not be formatted as an IP address"}},
+		{"aws", "storage.object", "-bucket-", []string{"must start with a lowercase letter or digit", "must end with a lowercase letter or digit"}},
+		{"gcp", "storage.object", "My-Google-Data", []string{"must not contain 'google'"}},
+		{"gcp", "compute.instance", "Web", []string{"may only contain lowercase letters, digits and '-'", "must start with a lowercase letter"}},
+		{"gcp", "compute.instance", strings.Repeat("a", 59), []string{"must be 1-58 characters long (has 59)"}},
+		{"azure", "storage.object", "MyStore", []string{"may only contain lowercase letters and digits"}},
+		{"azure", "compute.instance", "web.", []string{"must end with a letter, digit or '_'"}},
+		{"aws", "database.sql", "app--db", []string{"must not contain '--'"}},
+		{"aws", "network.load_balancer", "Internal-web", []string{"must not start with 'internal-'"}},
+		{"aws", "compute.function", "", []string{"must be 1-59 characters long (has 0)"}},
+	}
+	for _, tt := range tests {
+		rule := namingRules[tt.provider][tt.serviceType][0]
+		if got := rule.check(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s check(%q) = %q, want %q", tt.provider, tt.serviceType, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNamingRuleSuggest(t *testing.T) {
+	tests := []struct {
+		provider, serviceType, name string
+		want                        string
+	}{
+		{"aws", "storage.object", "My_Bucket", "my-bucket"},
+		{"aws", "storage.object", "a", "a00"},
+		{"gcp", "compute.instance", "1st__Web--Server-", "st-web-server"},
+		{"gcp", "compute.instance", strings.Repeat("ab-", 30), strings.Repeat("ab-", 19) + "a"},
+		{"azure", "storage.object", "my-store_01", "mystore01"},
+		{"azure", "compute.instance", "web server.", "web-server"},
+		{"aws", "storage.object", "192.168.0.1", ""},
+		{"gcp", "compute.instance", "123", ""},
+	}
+	for _, tt := range tests {
+		rule := namingRules[tt.provider][tt.serviceType][0]
+		got := rule.suggest(tt.name)
+		if got != tt.want {
+			t.Errorf("%s %s suggest(%q) = %q, want %q", tt.provider, tt.serviceType, tt.name, got, tt.want)
+		}
+		if got != "" && len(rule.check(got)) > 0 {
+			t.Errorf("%s %s suggest(%q) = %q, which is invalid: %v", tt.provider, tt.serviceType, tt.name, got, rule.check(got))
+		}
+	}
+}
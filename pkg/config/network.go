@@ -0,0 +1,393 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Subnet is a subnet of a network.vpc service. Without a CIDR the subnet gets
+// the next free range of PrefixLength (default 24) in the network.
+type Subnet struct {
+	Name         string `json:"name"`
+	CIDR         string `json:"cidr,omitempty"`
+	PrefixLength int    `json:"prefix_length,omitempty"`
+}
+
+// OutputRef points at an output of another resource of the plan. Key selects
+// an entry of a map output; List wraps a single value into a list. With
+// Resources, the output of each of them is collected into a list instead.
+type OutputRef struct {
+	Resource  string
+	Resources []string
+	Output    string
+	Key       string
+	List      bool
+}
+
+// subnetNameRe keeps subnet names valid on every provider; GCP subnetworks
+// are named "<network_id>-<name>".
+var subnetNameRe = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,22}[a-z0-9])?$`)
+
+// defaultSubnetPrefix is the prefix length of planned subnets.
+const defaultSubnetPrefix = 24
+
+// networkPrefixLimits are the network prefix lengths a provider accepts.
+var networkPrefixLimits = map[string][2]int{
+	"aws":   {16, 28},
+	"azure": {8, 29},
+	"gcp":   {8, 29},
+}
+
+// networkInputs maps the network inputs of each provider's compute module to
+// outputs of its network.vpc module. subnet_ids outputs are keyed by subnet
+// name.
+var networkInputs = map[string]map[string]string{
+	"aws":   {"vpc_id": "vpc_id", "subnet_id": "subnet_ids"},
+	"azure": {"subnet_id": "subnet_ids"},
+	"gcp":   {"network": "network", "subnetwork": "subnet_ids"},
+}
+
+// plannedSubnet is a subnet with its final CIDR.
+type plannedSubnet struct {
+	Name string
+	CIDR *net.IPNet
+}
+
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// nextSubnet returns the first range of the given prefix length in network
+// that overlaps none of used, or nil.
+func nextSubnet(network *net.IPNet, prefix int, used []*net.IPNet) *net.IPNet {
+	networkPrefix, bits := network.Mask.Size()
+	if prefix < networkPrefix || prefix > bits {
+		return nil
+	}
+	base := new(big.Int).SetBytes(network.IP.To4())
+	step := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefix))
+	count := new(big.Int).Lsh(big.NewInt(1), uint(prefix-networkPrefix))
+
+	for i := new(big.Int); i.Cmp(count) < 0; i.Add(i, big.NewInt(1)) {
+		start := new(big.Int).Add(base, new(big.Int).Mul(i, step))
+		ip := make(net.IP, 4)
+		start.FillBytes(ip)
+		candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, bits)}
+
+		free := true
+		for _, u := range used {
+			if cidrsOverlap(candidate, u) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// planSubnets assigns CIDRs to the subnets of a network.vpc service and
+// reports problems by service field. Without subnets, the network gets one
+// subnet named "default".
+func planSubnets(provider string, service Service) ([]plannedSubnet, map[string]string) {
+	problems := map[string]string{}
+
+	if err := checkCIDR(service.CIDR); err != nil {
+		problems["cidr"] = err.Error()
+		return nil, problems
+	}
+	_, network, _ := net.ParseCIDR(service.CIDR)
+	networkPrefix, _ := network.Mask.Size()
+	if limits, ok := networkPrefixLimits[provider]; ok && (networkPrefix < limits[0] || networkPrefix > limits[1]) {
+		problems["cidr"] = fmt.Sprintf("%s networks need a prefix length between /%d and /%d", provider, limits[0], limits[1])
+		return nil, problems
+	}
+
+	subnets := service.Subnets
+	if len(subnets) == 0 {
+		subnets = []Subnet{{Name: "default"}}
+	}
+
+	planned := make([]plannedSubnet, len(subnets))
+	var used []*net.IPNet
+	names := map[string]bool{}
+	for j, subnet := range subnets {
+		field := fmt.Sprintf("subnets.%d", j)
+		if !subnetNameRe.MatchString(subnet.Name) {
+			problems[field+".name"] = fmt.Sprintf("subnet name %q must be 1-24 lowercase letters, digits and '-', starting with a letter", subnet.Name)
+		} else if names[subnet.Name] {
+			problems[field+".name"] = fmt.Sprintf("subnet name %q is used twice", subnet.Name)
+		}
+		if provider == "gcp" && len(service.NetworkID)+1+len(subnet.Name) > 63 {
+			problems[field+".name"] = fmt.Sprintf("GCP subnetwork name %q is longer than 63 characters", service.NetworkID+"-"+subnet.Name)
+		}
+		names[subnet.Name] = true
+		planned[j].Name = subnet.Name
+
+		if subnet.CIDR == "" {
+			continue
+		}
+		if subnet.PrefixLength != 0 {
+			problems[field+".prefix_length"] = "prefix_length cannot be combined with cidr"
+		}
+		if err := checkCIDR(subnet.CIDR); err != nil {
+			problems[field+".cidr"] = err.Error()
+			continue
+		}
+		_, cidr, _ := net.ParseCIDR(subnet.CIDR)
+		if prefix, _ := cidr.Mask.Size(); !network.Contains(cidr.IP) || prefix < networkPrefix {
+			problems[field+".cidr"] = fmt.Sprintf("subnet %s is not within the network %s", cidr, network)
+			continue
+		}
+		for k, other := range planned[:j] {
+			if other.CIDR != nil && cidrsOverlap(cidr, other.CIDR) {
+				problems[field+".cidr"] = fmt.Sprintf("subnet %s overlaps subnet %q (%s)", cidr, subnets[k].Name, other.CIDR)
+			}
+		}
+		planned[j].CIDR = cidr
+		used = append(used, cidr)
+	}
+
+	// Plan the remaining subnets around the explicit ones
+	for j, subnet := range subnets {
+		if subnet.CIDR != "" {
+			continue
+		}
+		prefix := subnet.PrefixLength
+		if prefix == 0 {
+			prefix = max(defaultSubnetPrefix, networkPrefix)
+		}
+		cidr := nextSubnet(network, prefix, used)
+		if cidr == nil {
+			problems[fmt.Sprintf("subnets.%d", j)] = fmt.Sprintf("no free /%d range left in the network %s", prefix, network)
+			continue
+		}
+		planned[j].CIDR = cidr
+		used = append(used, cidr)
+	}
+	return planned, problems
+}
+
+// validateNetworks checks the subnets of all network.vpc services and that
+// their address spaces don't overlap, which would rule out peering them.
+// Copies of a network across regions share its address space.
+func validateNetworks(doc *document, config Config) []string {
+	var problems []string
+
+	type network struct {
+		service int
+		id      string
+		field   string
+		cidr    *net.IPNet
+	}
+	var networks []network
+
+	for i, configured := range config.Services {
+		if configured.Type != "network.vpc" {
+			continue
+		}
+		_, subnetProblems := planSubnets(config.serviceProvider(configured), configured)
+		fields := make([]string, 0, len(subnetProblems))
+		for field := range subnetProblems {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc.location(fmt.Sprintf("services.%d.%s", i, field)), subnetProblems[field]))
+		}
+		if _, ok := subnetProblems["cidr"]; ok {
+			continue
+		}
+
+		_, cidr, _ := net.ParseCIDR(configured.CIDR)
+		for _, service := range expandRegions(configured) {
+			field := fmt.Sprintf("services.%d.cidr", i)
+			for _, other := range networks {
+				if other.service != i && cidrsOverlap(cidr, other.cidr) {
+					problems = append(problems, fmt.Sprintf("%s: network %q (%s) overlaps network %q (%s) at %s", doc.location(field), service.NetworkID, cidr, other.id, other.cidr, doc.location(other.field)))
+				}
+			}
+			networks = append(networks, network{service: i, id: service.NetworkID, field: field, cidr: cidr})
+		}
+	}
+	return problems
+}
+
+// subnetValues renders the planned subnets as the subnets input of the
+// network.vpc modules, which generator_config.json reads with
+// "source": "network".
+func subnetValues(provider string, service Service) map[string]interface{} {
+	planned, _ := planSubnets(provider, service)
+	subnets := make([]interface{}, 0, len(planned))
+	for _, subnet := range planned {
+		subnets = append(subnets, map[string]interface{}{
+			"name": subnet.Name,
+			"cidr": subnet.CIDR.String(),
+		})
+	}
+	return map[string]interface{}{"subnets": subnets}
+}
+
+// linkNetworks resolves the network references of compute instances into
+// module inputs and dependencies. subnets holds the subnet names of every
+// network by resource ID.
+func linkNetworks(plan *ProvisioningPlan, services []Service, subnets map[string][]string, locations []string) error {
+	var problems []string
+	for i, service := range services {
+		if service.Network == "" {
+			continue
+		}
+		res := &plan.Resources[i]
+
+		// Instances copied across regions join the copy of the network in
+		// their region
+		var network *ResourcePlan
+		for j := range plan.Resources {
+			candidate := &plan.Resources[j]
+			if candidate.Type != "network.vpc" {
+				continue
+			}
+			if candidate.ID == service.Network || (network == nil && candidate.ID == service.Network+"-"+regionSuffix(res.Region)) {
+				network = candidate
+			}
+		}
+		switch {
+		case network == nil:
+			problems = append(problems, fmt.Sprintf("%s: unknown network %q", locations[i], service.Network))
+			continue
+		case network.Provider != res.Provider:
+			problems = append(problems, fmt.Sprintf("%s: network %q is on %s, %s is on %s", locations[i], network.ID, network.Provider, res.ID, res.Provider))
+			continue
+		case network.Region != res.Region:
+			problems = append(problems, fmt.Sprintf("%s: network %q is in %s, %s is in %s", locations[i], network.ID, network.Region, res.ID, res.Region))
+			continue
+		}
+
+		subnet := service.Subnet
+		if subnet == "" {
+			subnet = subnets[network.ID][0]
+		} else if !containsString(subnets[network.ID], subnet) {
+			problems = append(problems, fmt.Sprintf("%s: network %q has no subnet %q (subnets: %s)", locations[i], network.ID, subnet, strings.Join(subnets[network.ID], ", ")))
+			continue
+		}
+
+		res.DependsOn = append(res.DependsOn, network.ID)
+		if res.Inputs == nil {
+			res.Inputs = map[string]OutputRef{}
+		}
+		for input, output := range networkInputs[res.Provider] {
+			ref := OutputRef{Resource: network.ID, Output: output}
+			if output == "subnet_ids" {
+				ref.Key = subnet
+			}
+			res.Inputs[input] = ref
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// orderResources sorts resources so that every resource comes after the
+// ones it depends on, keeping the config order otherwise. It fails if user
+// data would be rendered before the resources it takes outputs from.
+func orderResources(resources []ResourcePlan) ([]ResourcePlan, error) {
+	ordered := make([]ResourcePlan, 0, len(resources))
+	placed := map[string]bool{}
+	for len(ordered) < len(resources) {
+		progress := false
+		for _, res := range resources {
+			if placed[res.ID] {
+				continue
+			}
+			ready := true
+			for _, dep := range res.DependsOn {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, res)
+				placed[res.ID] = true
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			var pending []string
+			for _, res := range resources {
+				if !placed[res.ID] {
+					pending = append(pending, res.ID)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(pending, ", "))
+		}
+	}
+
+	// User data is rendered with the outputs of the resources provisioned
+	// before the instance, so they must still come first
+	position := map[string]int{}
+	for i, res := range ordered {
+		position[res.ID] = i
+	}
+	var problems []string
+	for i, res := range ordered {
+		if res.UserData == nil {
+			continue
+		}
+		for _, id := range res.UserData.resources {
+			if j, ok := position[id]; !ok || j > i {
+				problems = append(problems, fmt.Sprintf("%s: the outputs of %q are used before it is provisioned", res.UserData.Name, id))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return ordered, nil
+}
+
+// ResolveInputs looks up the inputs of a resource in the outputs of the
+// resources provisioned before it, keyed by resource ID.
+func (r ResourcePlan) ResolveInputs(outputs map[string]map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for input, ref := range r.Inputs {
+		if len(ref.Resources) > 0 {
+			var list []interface{}
+			for _, resource := range ref.Resources {
+				value, ok := outputs[resource][ref.Output]
+				if !ok {
+					return nil, fmt.Errorf("output %q of %s is not available (was it provisioned?)", ref.Output, resource)
+				}
+				list = append(list, value)
+			}
+			values[input] = list
+			continue
+		}
+
+		value, ok := outputs[ref.Resource][ref.Output]
+		if !ok {
+			return nil, fmt.Errorf("output %q of %s is not available (was it provisioned?)", ref.Output, ref.Resource)
+		}
+		if ref.Key != "" {
+			entries, _ := value.(map[string]interface{})
+			if value, ok = entries[ref.Key]; !ok {
+				return nil, fmt.Errorf("output %q of %s has no entry %q", ref.Output, ref.Resource, ref.Key)
+			}
+		}
+		if _, isList := value.([]interface{}); ref.List && !isList {
+			value = []interface{}{value}
+		}
+		values[input] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func mustCIDR(t *testing.T, cidr string) *net.IPNet {
+	t.Helper()
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
+
+func TestNextSubnet(t *testing.T) {
+	tests := []struct {
+		network string
+		prefix  int
+		used    []string
+		want    string
+	}{
+		{"10.0.0.0/16", 24, nil, "10.0.0.0/24"},
+		{"10.0.0.0/16", 24, []string{"10.0.0.0/24"}, "10.0.1.0/24"},
+		{"10.0.0.0/16", 24, []string{"10.0.0.0/23", "10.0.3.0/24"}, "10.0.2.0/24"},
+		{"10.0.0.0/16", 23, []string{"10.0.1.0/24"}, "10.0.2.0/23"},
+		{"10.0.0.0/16", 16, nil, "10.0.0.0/16"},
+		{"10.0.0.0/24", 25, []string{"10.0.0.0/25", "10.0.0.128/25"}, ""},
+		{"10.0.0.0/16", 15, nil, ""},
+		{"10.0.0.0/16", 33, nil, ""},
+	}
+	for _, tt := range tests {
+		var used []*net.IPNet
+		for _, cidr := range tt.used {
+			used = append(used, mustCIDR(t, cidr))
+		}
+		got := ""
+		if subnet := nextSubnet(mustCIDR(t, tt.network), tt.prefix, used); subnet != nil {
+			got = subnet.String()
+		}
+		if got != tt.want {
+			t.Errorf("nextSubnet(%s, /%d, %v) = %q, want %q", tt.network, tt.prefix, tt.used, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSubnets(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		service  Service
+		want     []string
+		problems []string
+	}{
+		{"default subnet", "aws", Service{NetworkID: "net", CIDR: "10.0.0.0/16"}, []string{"default=10.0.0.0/24"}, nil},
+		{"small network", "gcp", Service{NetworkID: "net", CIDR: "10.0.0.0/26"}, []string{"default=10.0.0.0/26"}, nil},
+		{"planned around explicit", "aws", Service{NetworkID: "net", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+			{Name: "web"},
+			{Name: "db", CIDR: "10.0.0.0/24"},
+			{Name: "big", PrefixLength: 20},
+		}}, []string{"web=10.0.1.0/24", "db=10.0.0.0/24", "big=10.0.16.0/20"}, nil},
+		{"aws prefix limit", "aws", Service{NetworkID: "net", CIDR: "10.0.0.0/8"}, nil, []string{"cidr"}},
+		{"invalid network", "aws", Service{NetworkID: "net", CIDR: "10.0.0.1/16"}, nil, []string{"cidr"}},
+		{"subnet problems", "aws", Service{NetworkID: "net", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+			{Name: "a", CIDR: "10.0.0.0/24"},
+			{Name: "a", CIDR: "10.0.0.128/25"},
+			{Name: "Outside", CIDR: "10.1.0.0/24"},
+			{Name: "both", CIDR: "10.0.5.0/24", PrefixLength: 24},
+			{Name: "full", PrefixLength: 16},
+		}}, []string{"a=10.0.0.0/24", "a=10.0.0.128/25", "Outside=<nil>", "both=10.0.5.0/24", "full=<nil>"}, []string{
+			"subnets.1.cidr", "subnets.1.name", "subnets.2.cidr", "subnets.2.name", "subnets.3.prefix_length", "subnets.4",
+		}},
+		{"gcp subnetwork name length", "gcp", Service{NetworkID: strings.Repeat("n", 50), CIDR: "10.0.0.0/16", Subnets: []Subnet{{Name: "subnet-name-too-long"}}},
+			[]string{"subnet-name-too-long=10.0.0.0/24"}, []string{"subnets.0.name"}},
+	}
+	for _, tt := range tests {
+		planned, problems := planSubnets(tt.provider, tt.service)
+		var got []string
+		for _, subnet := range planned {
+			cidr := "<nil>"
+			if subnet.CIDR != nil {
+				cidr = subnet.CIDR.String()
+			}
+			got = append(got, subnet.Name+"="+cidr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: subnets %v, want %v", tt.name, got, tt.want)
+		}
+
+		var fields []string
+		for field := range problems {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		if !reflect.DeepEqual(fields, tt.problems) {
+			t.Errorf("%s: problems %v, want fields %v", tt.name, problems, tt.problems)
+		}
+	}
+}
+
+func TestValidateNetworks(t *testing.T) {
+	doc := &document{Path: "config.json"}
+	network := func(id, cidr string, regions ...string) Service {
+		return Service{Type: "network.vpc", NetworkID: id, CIDR: cidr, Regions: regions}
+	}
+
+	tests := []struct {
+		name     string
+		services []Service
+		problems int
+	}{
+		{"separate networks", []Service{network("a", "10.0.0.0/16"), network("b", "10.1.0.0/16")}, 0},
+		{"copies share their address space", []Service{network("a", "10.0.0.0/16", "eu-west-1", "us-east-1")}, 0},
+		{"overlapping networks", []Service{network("a", "10.0.0.0/16"), network("b", "10.0.128.0/17")}, 1},
+		{"overlap with each copy", []Service{network("a", "10.0.0.0/16", "eu-west-1", "us-east-1"), network("b", "10.0.0.0/16")}, 2},
+		{"invalid network skipped", []Service{network("a", "10.0.0.0/33"), network("b", "10.0.0.0/16")}, 1},
+	}
+	for _, tt := range tests {
+		problems := validateNetworks(doc, Config{Provider: "aws", Services: tt.services})
+		if len(problems) != tt.problems {
+			t.Errorf("%s: got %d problems, want %d: %v", tt.name, len(problems), tt.problems, problems)
+		}
+	}
+}
+
+func TestOrderResources(t *testing.T) {
+	userData := &UserData{Name: "services.1.user_data", resources: []string{"db"}}
+	tests := []struct {
+		name      string
+		resources []ResourcePlan
+		want      []string
+		err       string
+	}{
+		{"config order without dependencies", []ResourcePlan{{ID: "a"}, {ID: "b"}}, []string{"a", "b"}, ""},
+		{"dependencies first", []ResourcePlan{
+			{ID: "db", DependsOn: []string{"net"}},
+			{ID: "app", DependsOn: []string{"db"}, UserData: userData},
+			{ID: "net"},
+		}, []string{"net", "db", "app"}, ""},
+		{"user data outputs not provisioned first", []ResourcePlan{
+			{ID: "db", DependsOn: []string{"net"}},
+			{ID: "app", UserData: userData},
+			{ID: "net"},
+		}, nil, `services.1.user_data: the outputs of "db" are used before it is provisioned`},
+		{"cycle", []ResourcePlan{{ID: "a", DependsOn: []string{"b"}}, {ID: "b", DependsOn: []string{"a"}}}, nil, "dependency cycle between a, b"},
+	}
+	for _, tt := range tests {
+		ordered, err := orderResources(tt.resources)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var ids []string
+		for _, res := range ordered {
+			ids = append(ids, res.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: order %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envNameRe restricts environment names to values safe in file and directory names.
+var envNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PlanOptions adjust how a config file is resolved into a plan.
+type PlanOptions struct {
+	// Env selects an overlay (e.g. "prod" loads project.prod.json over project.json).
+	Env string
+}
+
+// overlayPath returns the overlay file for env next to configPath. The overlay
+// may use any supported format; the base file's format is preferred.
+func overlayPath(configPath, env string) (string, error) {
+	ext := filepath.Ext(configPath)
+	stem := strings.TrimSuffix(configPath, ext)
+
+	candidates := []string{stem + "." + env + ext}
+	for _, supported := range SupportedExtensions {
+		if supported != strings.ToLower(ext) {
+			candidates = append(candidates, stem+"."+env+supported)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no overlay for environment %q found (expected %s)", env, candidates[0])
+}
+
+// resolveDocument loads the config, applies the environment overlay,
+// evaluates variables and expressions and expands count/for_each services.
+func resolveDocument(configPath string, opts PlanOptions) (*document, error) {
+	doc, err := loadDocument(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Env != "" {
+		if !envNameRe.MatchString(opts.Env) {
+			return nil, fmt.Errorf("invalid environment name %q (allowed: letters, digits, '-' and '_')", opts.Env)
+		}
+
+		path, err := overlayPath(configPath, opts.Env)
+		if err != nil {
+			return nil, err
+		}
+		overlay, err := loadDocument(path)
+		if err != nil {
+			return nil, err
+		}
+		doc.merge(overlay)
+	}
+
+	if err := interpolate(doc); err != nil {
+		return nil, err
+	}
+	if err := expandServices(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// RenderConfig returns the effective config after overlays, interpolation and
+// expansion as indented JSON, with secret values masked.
+func RenderConfig(configPath string, opts PlanOptions) ([]byte, error) {
+	doc, err := resolveDocument(configPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc.masked(), "", "  ")
+}
+
+// merge deep-merges overlay into d: objects are merged key by key, services
+// are matched by their ID and merged, and any other value is replaced.
+// Positions of values taken from the overlay point into the overlay file.
+func (d *document) merge(overlay *document) {
+	d.Data = d.mergeObject(d.Data, overlay.Data, rootField, rootField, overlay)
+}
+
+func (d *document) mergeObject(base, over map[string]interface{}, field, overField string, overlay *document) map[string]interface{} {
+	for key, overValue := range over {
+		childField := joinField(field, key)
+		childOverField := joinField(overField, key)
+
+		baseObj, baseIsObj := base[key].(map[string]interface{})
+		overObj, overIsObj := overValue.(map[string]interface{})
+		baseList, baseIsList := base[key].([]interface{})
+		overList, overIsList := overValue.([]interface{})
+
+		switch {
+		case baseIsObj && overIsObj:
+			base[key] = d.mergeObject(baseObj, overObj, childField, childOverField, overlay)
+		case key == "services" && field == rootField && baseIsList && overIsList:
+			base[key] = d.mergeServices(baseList, overList, overlay)
+		default:
+			base[key] = overValue
+			d.copyPositions(overlay, childOverField, childField)
+		}
+	}
+	return base
+}
+
+func (d *document) mergeServices(base, over []interface{}, overlay *document) []interface{} {
+	index := map[string]int{}
+	for i, item := range base {
+		if id := serviceKey(item); id != "" {
+			index[id] = i
+		}
+	}
+
+	for j, item := range over {
+		overField := joinField("services", strconv.Itoa(j))
+		overObj, isObj := item.(map[string]interface{})
+		if i, found := index[serviceKey(item)]; found && isObj {
+			if baseObj, ok := base[i].(map[string]interface{}); ok {
+				base[i] = d.mergeObject(baseObj, overObj, joinField("services", strconv.Itoa(i)), overField, overlay)
+				continue
+			}
+		}
+		d.copyPositions(overlay, overField, joinField("services", strconv.Itoa(len(base))))
+		base = append(base, item)
+	}
+	return base
+}
+
+// serviceKey identifies a service across base config and overlay.
+func serviceKey(item interface{}) string {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, field := range idFields {
+		if id, ok := obj[field].(string); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// copyPositions replaces the positions under field with those recorded for
+// srcField in src, keeping track of the file they came from.
+func (d *document) copyPositions(src *document, srcField, field string) {
+	for key := range d.positions {
+		if key == field || strings.HasPrefix(key, field+".") {
+			delete(d.positions, key)
+		}
+	}
+	for key, pos := range src.positions {
+		if key != srcField && !strings.HasPrefix(key, srcField+".") {
+			continue
+		}
+		if pos.File == "" {
+			pos.File = src.Path
+		}
+		d.positions[field+strings.TrimPrefix(key, srcField)] = pos
+	}
+}
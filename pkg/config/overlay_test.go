@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadTestDocument(t *testing.T, dir, name, content string) *document {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMergeServices(t *testing.T) {
+	base := `{
+  "project_name": "demo",
+  "services": [
+    {"type": "compute.instance", "instance_id": "web", "size": "small", "allowed_ports": [80, 443], "metadata": {"app": "web", "tier": "front"}},
+    {"type": "storage.object", "bucket_id": "assets", "versioning": false},
+    {"type": "compute.instance", "size": "small"}
+  ]
+}`
+	overlay := `{
+  "services": [
+    {"bucket_id": "assets", "versioning": true},
+    {"instance_id": "web", "size": "large", "allowed_ports": [443], "metadata": {"tier": "edge"}},
+    {"type": "storage.object", "bucket_id": "logs"},
+    {"type": "compute.instance", "size": "medium"}
+  ]
+}`
+	want := []interface{}{
+		map[string]interface{}{"type": "compute.instance", "instance_id": "web", "size": "large", "allowed_ports": []interface{}{443.0}, "metadata": map[string]interface{}{"app": "web", "tier": "edge"}},
+		map[string]interface{}{"type": "storage.object", "bucket_id": "assets", "versioning": true},
+		map[string]interface{}{"type": "compute.instance", "size": "small"},
+		map[string]interface{}{"type": "storage.object", "bucket_id": "logs"},
+		map[string]interface{}{"type": "compute.instance", "size": "medium"},
+	}
+
+	dir := t.TempDir()
+	doc := loadTestDocument(t, dir, "demo.json", base)
+	over := loadTestDocument(t, dir, "demo.prod.json", overlay)
+	doc.merge(over)
+
+	if got := doc.Data["services"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("services = %v, want %v", got, want)
+	}
+
+	// Overridden and added values point into the overlay, kept ones don't
+	positions := []struct {
+		field string
+		file  string
+		line  int
+	}{
+		{"services.0.size", over.Path, 4},
+		{"services.0.metadata.tier", over.Path, 4},
+		{"services.0.metadata.app", "", 4},
+		{"services.1.versioning", over.Path, 3},
+		{"services.3.bucket_id", over.Path, 5},
+		{"services.4", over.Path, 6},
+		{"services.2", "", 6},
+	}
+	for _, p := range positions {
+		pos, ok := doc.positions[p.field]
+		if !ok {
+			t.Errorf("%s: no position", p.field)
+			continue
+		}
+		if pos.File != p.file || pos.Line != p.line {
+			t.Errorf("%s: at %s:%d, want %s:%d", p.field, pos.File, pos.Line, p.file, p.line)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/xeipuuv/gojsonschema"
@@ -24,21 +26,108 @@ type ResourcePlan struct {
 	Type      string
 	TfVars    string
 	ModuleDir string
+	// Provider and Region are the config defaults unless the service overrides them.
+	Provider string
+	Region   string
+	// Zone is set for providers whose modules need one, or when configured.
+	Zone string
+	// GenerateSSHKey is set for compute instances without a configured
+	// ssh_public_key; the key pair is created when the resource is provisioned.
+	GenerateSSHKey bool
+	// UserData is rendered into the user_data tfvar when the resource is
+	// provisioned, after the resources before it.
+	UserData *UserData
+	// FunctionSource is the source directory of a compute.function; it is
+	// packaged into the source_zip tfvar when the resource is provisioned.
+	FunctionSource string
+	// DependsOn lists the resources that are provisioned before this one and
+	// destroyed after it; Inputs are tfvars taken from their outputs.
+	DependsOn []string
+	Inputs    map[string]OutputRef
+	// Sensitive is set when TfVars or UserData contain values from secret variables.
+	Sensitive bool
 }
 
 type ProvisioningPlan struct {
-	Provider  string
-	Region    string
-	OutputDir string
-	Resources []ResourcePlan
+	Provider           string
+	Region             string
+	Environment        string
+	OutputDir          string
+	CredentialsProfile string
+	Resources          []ResourcePlan
+}
+
+// Providers returns the cloud providers the plan provisions resources on, in
+// the order they first appear.
+func (p *ProvisioningPlan) Providers() []string {
+	var providers []string
+	seen := map[string]bool{}
+	for _, res := range p.Resources {
+		if !seen[res.Provider] {
+			seen[res.Provider] = true
+			providers = append(providers, res.Provider)
+		}
+	}
+	if len(providers) == 0 {
+		return []string{p.Provider}
+	}
+	return providers
+}
+
+// mixedProviderDir replaces the provider in the output path of plans that
+// span several providers.
+const mixedProviderDir = "multi"
+
+// outputDir returns the directory below base the resources of project are
+// provisioned in: <provider>/<project>, or multi/<project> for several
+// providers. A project keeps its directory when providers are added or
+// removed, so an existing directory of one of its providers or of the
+// multi-provider layout is used instead.
+func outputDir(base, project string, providers []string) (string, error) {
+	candidates := append(append([]string{}, providers...), mixedProviderDir)
+	var existing []string
+	for _, providerDir := range candidates {
+		dir := filepath.Join(base, providerDir, project)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			existing = append(existing, dir)
+		}
+	}
+
+	switch {
+	case len(existing) == 1:
+		return existing[0], nil
+	case len(existing) > 1:
+		return "", fmt.Errorf("project %q is provisioned in several directories (%s), move its resources into one of them", project, strings.Join(existing, ", "))
+	case len(providers) == 1:
+		return filepath.Join(base, providers[0], project), nil
+	}
+	return filepath.Join(base, mixedProviderDir, project), nil
 }
 
 type Service struct {
 	Type          string            `json:"type"`
+	Provider      string            `json:"provider,omitempty"`
+	Region        string            `json:"region,omitempty"`
+	Regions       []string          `json:"regions,omitempty"` // one copy per region, IDs suffixed with the region
+	Zone          string            `json:"zone,omitempty"`
 	InstanceID    string            `json:"instance_id,omitempty"`
 	BucketID      string            `json:"bucket_id,omitempty"`
+	NetworkID     string            `json:"network_id,omitempty"`
+	DatabaseID    string            `json:"database_id,omitempty"`
+	RecordID      string            `json:"record_id,omitempty"`
 	Size          string            `json:"size,omitempty"`
+	MachineType   string            `json:"machine_type,omitempty"`
 	OS            string            `json:"os,omitempty"`
+	Image         string            `json:"image,omitempty"` // provider image reference instead of os
+	UserData      string            `json:"user_data,omitempty"`
+	UserDataFile  string            `json:"user_data_file,omitempty"` // relative to the config file
+	AllowedPorts  []int             `json:"allowed_ports,omitempty"`  // TCP ports open to anywhere
+	FirewallRules []FirewallRule    `json:"firewall_rules,omitempty"`
+	SSH           *SSHAccess        `json:"ssh,omitempty"`
+	CIDR          string            `json:"cidr,omitempty"`    // network.vpc address space
+	Subnets       []Subnet          `json:"subnets,omitempty"` // network.vpc subnets
+	Network       string            `json:"network,omitempty"` // network_id of the network.vpc a compute.instance joins
+	Subnet        string            `json:"subnet,omitempty"`  // subnet of that network (default: the first)
 	DiskSizeGB    int               `json:"disk_size_gb,omitempty"`
 	Metadata      map[string]string `json:"metadata,omitempty"`
 	ProjectID     string            `json:"project_id,omitempty"`
@@ -46,16 +135,154 @@ type Service struct {
 	AdminUsername string            `json:"admin_username,omitempty"`
 	StorageTier   string            `json:"storage_tier,omitempty"`
 	Versioning    bool              `json:"versioning,omitempty"`
+	Engine        string            `json:"engine,omitempty"`         // database.sql engine (postgres, mysql)
+	EngineVersion string            `json:"engine_version,omitempty"` // default: the catalog's default version
+	StorageGB     int               `json:"storage_gb,omitempty"`
+	// BackupRetentionDays is a pointer so that 0 (no backups) is kept
+	BackupRetentionDays *int              `json:"backup_retention_days,omitempty"`
+	PublicAccess        bool              `json:"public_access,omitempty"`
+	AllowedCIDRs        []string          `json:"allowed_cidrs,omitempty"` // sources allowed to reach a public database
+	DatabaseName        string            `json:"database_name,omitempty"`
+	DNSZone             string            `json:"dns_zone,omitempty"`    // dns.record zone, e.g. example.com
+	RecordName          string            `json:"record_name,omitempty"` // relative to dns_zone, "@" for the apex
+	RecordType          string            `json:"record_type,omitempty"`
+	TTL                 int               `json:"ttl,omitempty"`
+	Values              []string          `json:"values,omitempty"`
+	ValueFrom           *RecordSource     `json:"value_from,omitempty"`
+	LoadBalancerID      string            `json:"load_balancer_id,omitempty"`
+	Backends            []string          `json:"backends,omitempty"` // compute.instance IDs behind a network.load_balancer
+	Listeners           []Listener        `json:"listeners,omitempty"`
+	HealthCheck         *HealthCheck      `json:"health_check,omitempty"`
+	TLS                 *LoadBalancerTLS  `json:"tls,omitempty"`
+	ClusterID           string            `json:"cluster_id,omitempty"`
+	NodeCount           int               `json:"node_count,omitempty"`         // container.cluster nodes (default 2)
+	KubernetesVersion   string            `json:"kubernetes_version,omitempty"` // e.g. "1.31"; the provider's default when omitted
+	FunctionID          string            `json:"function_id,omitempty"`
+	Runtime             string            `json:"runtime,omitempty"`         // compute.function runtime from the catalog
+	SourceDir           string            `json:"source_dir,omitempty"`      // relative to the config file
+	EntryPoint          string            `json:"entry_point,omitempty"`     // <file>.<function> on AWS, the function name elsewhere
+	MemoryMB            int               `json:"memory_mb,omitempty"`       // default 256
+	TimeoutSeconds      int               `json:"timeout_seconds,omitempty"` // default 60
+	Environment         map[string]string `json:"environment,omitempty"`
 	// Additional fields can be added here as needed
 }
 
 type Config struct {
 	Provider       string    `json:"provider"`
 	Region         string    `json:"region"`
+	Zone           string    `json:"zone,omitempty"`
 	ProjectName    string    `json:"project_name"`
 	Services       []Service `json:"services"`
 	SubscriptionID string    `json:"subscription_id,omitempty"`
 	Version        string    `json:"version,omitempty"`
+	// CredentialsProfile names a profile from profiles.json used for tofu runs.
+	CredentialsProfile string `json:"credentials_profile,omitempty"`
+}
+
+// serviceProvider returns the provider a service is provisioned on.
+func (c Config) serviceProvider(service Service) string {
+	if service.Provider != "" {
+		return service.Provider
+	}
+	return c.Provider
+}
+
+// serviceRegion returns the region a service is provisioned in.
+func (c Config) serviceRegion(service Service) string {
+	if service.Region != "" {
+		return service.Region
+	}
+	return c.Region
+}
+
+// serviceZone returns the configured zone of a service. The config's zone
+// only applies to services in the config's region.
+func (c Config) serviceZone(service Service) string {
+	if service.Zone != "" || service.Region != "" {
+		return service.Zone
+	}
+	return c.Zone
+}
+
+// regionSlugRe matches the characters replaced when a region becomes part of an ID.
+var regionSlugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// regionSuffix turns a region name into an ID suffix ("Sweden Central" -> "sweden-central").
+func regionSuffix(region string) string {
+	return strings.Trim(regionSlugRe.ReplaceAllString(strings.ToLower(region), "-"), "-")
+}
+
+// idFields are the service fields holding the resource ID, one per service type.
+var idFields = []string{"instance_id", "bucket_id", "network_id", "database_id", "record_id", "load_balancer_id", "cluster_id", "function_id"}
+
+// resourceID returns the configured ID of a service and the field holding it.
+func (s Service) resourceID() (string, string) {
+	for _, field := range idFields {
+		var id string
+		switch field {
+		case "instance_id":
+			id = s.InstanceID
+		case "bucket_id":
+			id = s.BucketID
+		case "network_id":
+			id = s.NetworkID
+		case "database_id":
+			id = s.DatabaseID
+		case "record_id":
+			id = s.RecordID
+		case "load_balancer_id":
+			id = s.LoadBalancerID
+		case "cluster_id":
+			id = s.ClusterID
+		case "function_id":
+			id = s.FunctionID
+		}
+		if id != "" {
+			return id, field
+		}
+	}
+	return "", ""
+}
+
+// expandRegions returns one copy of a service per entry of its regions list,
+// with the region set and the region appended to its ID.
+func expandRegions(service Service) []Service {
+	if len(service.Regions) == 0 {
+		return []Service{service}
+	}
+
+	services := make([]Service, 0, len(service.Regions))
+	for _, region := range service.Regions {
+		stamped := service
+		stamped.Regions = nil
+		stamped.Region = region
+		if stamped.InstanceID != "" {
+			stamped.InstanceID += "-" + regionSuffix(region)
+		}
+		if stamped.BucketID != "" {
+			stamped.BucketID += "-" + regionSuffix(region)
+		}
+		if stamped.NetworkID != "" {
+			stamped.NetworkID += "-" + regionSuffix(region)
+		}
+		if stamped.DatabaseID != "" {
+			stamped.DatabaseID += "-" + regionSuffix(region)
+		}
+		if stamped.RecordID != "" {
+			stamped.RecordID += "-" + regionSuffix(region)
+		}
+		if stamped.LoadBalancerID != "" {
+			stamped.LoadBalancerID += "-" + regionSuffix(region)
+		}
+		if stamped.ClusterID != "" {
+			stamped.ClusterID += "-" + regionSuffix(region)
+		}
+		if stamped.FunctionID != "" {
+			stamped.FunctionID += "-" + regionSuffix(region)
+		}
+		services = append(services, stamped)
+	}
+	return services
 }
 
 var generatorConfig map[string]map[string][]AttributeConfig
@@ -74,13 +301,20 @@ func LoadGeneratorConfig(rootPath string) error {
 	return nil
 }
 
-func loadSchema(schemaPath string) (*gojsonschema.Schema, error) {
-	absPath, err := filepath.Abs(schemaPath)
+// loadSchema reads the schema and fills in the size and os enums from the catalog.
+func loadSchema(schemaPath string, catalog *Catalog) (*gojsonschema.Schema, error) {
+	data, err := os.ReadFile(schemaPath)
 	if err != nil {
-		return nil, fmt.Errorf("error getting absolute path: %w", err)
+		return nil, fmt.Errorf("error reading schema: %w", err)
 	}
 
-	schemaLoader := gojsonschema.NewReferenceLoader("file://" + absPath)
+	var schemaDoc map[string]interface{}
+	if err := json.Unmarshal(data, &schemaDoc); err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+	catalog.injectEnums(schemaDoc)
+
+	schemaLoader := gojsonschema.NewGoLoader(schemaDoc)
 	schema, err := gojsonschema.NewSchema(schemaLoader)
 	if err != nil {
 		return nil, fmt.Errorf("error loading schema: %w", err)
@@ -89,7 +323,12 @@ func loadSchema(schemaPath string) (*gojsonschema.Schema, error) {
 	return schema, nil
 }
 
-func validateConfig(schema *gojsonschema.Schema, configData []byte) (bool, string) {
+func validateConfig(schema *gojsonschema.Schema, doc *document) (bool, string) {
+	configData, err := doc.JSON()
+	if err != nil {
+		return false, fmt.Sprintf("validation error: %v", err)
+	}
+
 	documentLoader := gojsonschema.NewBytesLoader(configData)
 	result, err := schema.Validate(documentLoader)
 	if err != nil {
@@ -99,7 +338,11 @@ func validateConfig(schema *gojsonschema.Schema, configData []byte) (bool, strin
 	if !result.Valid() {
 		var errors []string
 		for _, desc := range result.Errors() {
-			errors = append(errors, desc.String())
+			// The specific errors of a failed if/then or allOf are reported on their own
+			if desc.Type() == "condition_then" || desc.Type() == "number_all_of" {
+				continue
+			}
+			errors = append(errors, fmt.Sprintf("%s: %s", doc.location(desc.Field()), desc.Description()))
 		}
 		return false, strings.Join(errors, "; ")
 	}
@@ -111,14 +354,68 @@ func validateConfig(schema *gojsonschema.Schema, configData []byte) (bool, strin
 	}
 
 	// provider-specific validation
-	if config.Provider == "gcp" {
-		for _, service := range config.Services {
-			if service.Type == "compute.instance" && service.ProjectID == "" {
-				return false, "GCP compute.instance requires 'project_id' in service configuration"
+	for i, service := range config.Services {
+		if config.serviceProvider(service) == "gcp" && service.Type != "storage.object" && service.ProjectID == "" {
+			return false, fmt.Sprintf("%s: GCP %s requires 'project_id' in service configuration", doc.location(fmt.Sprintf("services.%d", i)), service.Type)
+		}
+		// Azure subscription ID is optional (env var), so no mandatory check here
+
+		if service.Type == "compute.instance" && (service.Size == "") == (service.MachineType == "") {
+			return false, fmt.Sprintf("%s: compute.instance requires exactly one of 'size' or 'machine_type'", doc.location(fmt.Sprintf("services.%d", i)))
+		}
+		if service.Type == "container.cluster" && (service.Size == "") == (service.MachineType == "") {
+			return false, fmt.Sprintf("%s: container.cluster requires exactly one of 'size' or 'machine_type'", doc.location(fmt.Sprintf("services.%d", i)))
+		}
+		if service.Type == "compute.instance" && (service.OS == "") == (service.Image == "") {
+			return false, fmt.Sprintf("%s: compute.instance requires exactly one of 'os' or 'image'", doc.location(fmt.Sprintf("services.%d", i)))
+		}
+
+		if service.UserData != "" && service.UserDataFile != "" {
+			return false, fmt.Sprintf("%s: 'user_data' and 'user_data_file' cannot both be set", doc.location(fmt.Sprintf("services.%d.user_data_file", i)))
+		}
+
+		if service.Type == "compute.instance" {
+			if problems := validateFirewall(doc, i, service); len(problems) > 0 {
+				return false, strings.Join(problems, "; ")
 			}
 		}
-	} else if config.Provider == "azure" {
-		// Subscription ID is now optional (env var), so no mandatory check here
+		if service.Type == "database.sql" {
+			if problems := validateDatabase(doc, i, config.serviceProvider(service), service); len(problems) > 0 {
+				return false, strings.Join(problems, "; ")
+			}
+		}
+		if service.Type == "dns.record" {
+			if problems := validateRecord(doc, i, service); len(problems) > 0 {
+				return false, strings.Join(problems, "; ")
+			}
+		}
+		if service.Type == "network.load_balancer" {
+			if problems := validateLoadBalancer(doc, i, config.serviceProvider(service), service); len(problems) > 0 {
+				return false, strings.Join(problems, "; ")
+			}
+		}
+		if service.Type == "compute.function" {
+			if problems := validateFunction(doc, i, config.serviceProvider(service), service); len(problems) > 0 {
+				return false, strings.Join(problems, "; ")
+			}
+		}
+
+		if service.Region != "" && len(service.Regions) > 0 {
+			return false, fmt.Sprintf("%s: 'region' and 'regions' cannot both be set", doc.location(fmt.Sprintf("services.%d.regions", i)))
+		}
+	}
+
+	// Names the provider would reject during apply
+	if problems := validateNames(doc, config); len(problems) > 0 {
+		return false, strings.Join(problems, "; ")
+	}
+
+	if problems := validateNetworks(doc, config); len(problems) > 0 {
+		return false, strings.Join(problems, "; ")
+	}
+
+	if problems := validateRecords(doc, config); len(problems) > 0 {
+		return false, strings.Join(problems, "; ")
 	}
 
 	return true, ""
@@ -127,7 +424,7 @@ func validateConfig(schema *gojsonschema.Schema, configData []byte) (bool, strin
 func formatTfvarsValue(value interface{}) string {
 	switch v := value.(type) {
 	case string:
-		return fmt.Sprintf(`"%s"`, v)
+		return quoteTfString(v)
 	case float64:
 		if v == float64(int64(v)) {
 			return fmt.Sprintf("%d", int64(v))
@@ -138,9 +435,15 @@ func formatTfvarsValue(value interface{}) string {
 	case bool:
 		return fmt.Sprintf("%v", v)
 	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
 		var items []string
-		for k, val := range v {
-			items = append(items, fmt.Sprintf("  %s = %s", k, formatTfvarsValue(val)))
+		for _, k := range keys {
+			items = append(items, fmt.Sprintf("  %s = %s", quoteTfString(k), formatTfvarsValue(v[k])))
 		}
 		return "{\n" + strings.Join(items, "\n") + "\n}"
 	case []interface{}:
@@ -149,11 +452,49 @@ func formatTfvarsValue(value interface{}) string {
 			items = append(items, formatTfvarsValue(item))
 		}
 		return "[" + strings.Join(items, ", ") + "]"
+	case []string:
+		var items []string
+		for _, item := range v {
+			items = append(items, quoteTfString(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
 	default:
 		return fmt.Sprintf("%v", v)
 	}
 }
 
+// tfStringEscaper escapes quotes, control characters and template sequences
+// so that values (e.g. file contents or interpolated variables) are written
+// literally.
+var tfStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"${", "$${",
+	"%{", "%%{",
+)
+
+func quoteTfString(s string) string {
+	return `"` + tfStringEscaper.Replace(s) + `"`
+}
+
+// AppendTfvars adds module inputs that are only known at provisioning time.
+func AppendTfvars(tfvars string, vars map[string]interface{}) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s = %s", k, formatTfvarsValue(vars[k])))
+	}
+	return tfvars + strings.Join(lines, "\n") + "\n"
+}
+
 // structToMap converts a struct to a map[string]interface{} using JSON marshaling
 func structToMap(v interface{}) (map[string]interface{}, error) {
 	data, err := json.Marshal(v)
@@ -165,7 +506,9 @@ func structToMap(v interface{}) (map[string]interface{}, error) {
 	return m, err
 }
 
-func generateTfvars(provider, serviceType string, config Config, service Service) (string, error) {
+// generateTfvars renders the module inputs of a service. derived holds the
+// values computed in Go by source name (e.g. "catalog").
+func generateTfvars(provider, serviceType string, config Config, service Service, derived map[string]map[string]interface{}) (string, error) {
 	var lines []string
 
 	// Get attribute configuration based on provider & service
@@ -192,8 +535,12 @@ func generateTfvars(provider, serviceType string, config Config, service Service
 	for _, attr := range attrs {
 		// Determine source map
 		sourceMap := serviceMap
-		if attr.Source == "config" {
+		switch attr.Source {
+		case "config":
 			sourceMap = configMap
+		case "", "service":
+		default:
+			sourceMap = derived[attr.Source]
 		}
 
 		// Get source field name (use mapping if specified, otherwise use field name)
@@ -239,36 +586,57 @@ func GetServiceFolderName(serviceType string) string {
 		return "compute_instance"
 	case "storage.object":
 		return "storage_object"
+	case "network.vpc":
+		return "network_vpc"
+	case "database.sql":
+		return "database_sql"
+	case "dns.record":
+		return "dns_record"
+	case "network.load_balancer":
+		return "network_load_balancer"
+	case "container.cluster":
+		return "container_cluster"
+	case "compute.function":
+		return "compute_function"
 	}
 	return strings.ReplaceAll(serviceType, ".", "_")
 }
 
-// GeneratePlan parses the config and returns a provisioning plan.
-func GeneratePlan(configPath string, rootPath string) (*ProvisioningPlan, error) {
-	// Load configuration
-	configData, err := os.ReadFile(configPath)
+// GeneratePlan parses the config, applies the environment overlay selected in
+// opts and returns a provisioning plan.
+func GeneratePlan(configPath string, rootPath string, opts PlanOptions) (*ProvisioningPlan, error) {
+	// Load configuration (JSON, YAML or HCL) and apply the overlay
+	doc, err := resolveDocument(configPath, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error loading configuration: %w", err)
+		return nil, err
 	}
 
 	// Load and validate schema
 	schemaPath := filepath.Join(rootPath, "parser", "schema.json")
-	schema, err := loadSchema(schemaPath)
+	catalog, err := LoadCatalog(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := loadSchema(schemaPath, catalog)
 	if err != nil {
 		return nil, fmt.Errorf("error loading schema: %w", err)
 	}
 
-	isValid, errorMsg := validateConfig(schema, configData)
+	isValid, errorMsg := validateConfig(schema, doc)
 	if !isValid {
 		return nil, fmt.Errorf("validation failed: %s", errorMsg)
 	}
 
 	// Unmarshal into Config struct
+	configData, err := doc.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding configuration: %w", err)
+	}
 	var config Config
 	if err := json.Unmarshal(configData, &config); err != nil {
-		return nil, fmt.Errorf("error parsing configuration JSON: %w", err)
+		return nil, fmt.Errorf("error parsing configuration: %w", err)
 	}
-	
+
 	if config.ProjectName == "" {
 		return nil, fmt.Errorf("validation error: 'project_name' is required in configuration")
 	}
@@ -277,39 +645,178 @@ func GeneratePlan(configPath string, rootPath string) (*ProvisioningPlan, error)
 	sanitizedProjectName := strings.ReplaceAll(config.ProjectName, " ", "_")
 	sanitizedProjectName = strings.ReplaceAll(sanitizedProjectName, "/", "-")
 
-	outputDir := filepath.Join(rootPath, "provisioning", config.Provider, sanitizedProjectName)
+	// Validate and normalize regions against the offline catalog
+	regionCatalog, err := LoadRegionCatalog(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	var regionProblems []string
+	if region, zone, err := regionCatalog.Resolve(config.Provider, config.Region, config.Zone); err != nil {
+		regionProblems = append(regionProblems, fmt.Sprintf("%s: %v", doc.location("region"), err))
+	} else {
+		config.Region, config.Zone = region, zone
+	}
 
 	plan := &ProvisioningPlan{
-		Provider:  config.Provider,
-		Region:    config.Region,
-		OutputDir: outputDir,
-		Resources: []ResourcePlan{},
+		Provider:           config.Provider,
+		Region:             config.Region,
+		Environment:        opts.Env,
+		CredentialsProfile: config.CredentialsProfile,
+		Resources:          []ResourcePlan{},
 	}
 
 	// Process each service
-	for _, service := range config.Services {
-		tfvarsContent, err := generateTfvars(config.Provider, service.Type, config, service)
-		if err != nil {
-			return nil, fmt.Errorf("error generating tfvars for service type %s: %w", service.Type, err)
+	var idLocations, networkLocations, recordLocations, backendLocations []string
+	var services []Service
+	subnets := map[string][]string{}
+	for i, configured := range config.Services {
+		for _, service := range expandRegions(configured) {
+			// Services may override the provider and region of the config
+			serviceConfig := config
+			serviceConfig.Provider = config.serviceProvider(service)
+			serviceConfig.Region = config.serviceRegion(service)
+			serviceConfig.Zone = config.serviceZone(service)
+			if serviceConfig.Provider != config.Provider || configured.Region != "" || len(configured.Regions) > 0 || configured.Zone != "" {
+				field := fmt.Sprintf("services.%d.provider", i)
+				switch {
+				case len(configured.Regions) > 0:
+					field = fmt.Sprintf("services.%d.regions", i)
+				case configured.Region != "":
+					field = fmt.Sprintf("services.%d.region", i)
+				case configured.Zone != "":
+					field = fmt.Sprintf("services.%d.zone", i)
+				}
+				if region, zone, err := regionCatalog.Resolve(serviceConfig.Provider, serviceConfig.Region, serviceConfig.Zone); err != nil {
+					regionProblems = append(regionProblems, fmt.Sprintf("%s: %v", doc.location(field), err))
+				} else {
+					serviceConfig.Region, serviceConfig.Zone = region, zone
+				}
+			}
+
+			// Determine ID
+			id, field := service.resourceID()
+			idField := fmt.Sprintf("services.%d", i)
+			if id != "" {
+				idField += "." + field
+			} else {
+				// Fallback ID
+				id = fmt.Sprintf("%s-%d", GetServiceFolderName(service.Type), len(plan.Resources)+1)
+			}
+			idLocations = append(idLocations, doc.location(idField))
+
+			catalogValues, field, err := catalog.serviceValues(serviceConfig.Provider, service)
+			if err != nil {
+				return nil, fmt.Errorf("validation failed: %s: %v", doc.location(fmt.Sprintf("services.%d.%s", i, field)), err)
+			}
+
+			derived := map[string]map[string]interface{}{"catalog": catalogValues}
+			switch service.Type {
+			case "compute.instance":
+				derived["firewall"] = firewallValues(serviceConfig.Provider, service)
+			case "network.vpc":
+				derived["network"] = subnetValues(serviceConfig.Provider, service)
+				planned, _ := planSubnets(serviceConfig.Provider, service)
+				for _, subnet := range planned {
+					subnets[id] = append(subnets[id], subnet.Name)
+				}
+			case "network.load_balancer":
+				derived["load_balancer"] = loadBalancerValues(serviceConfig.Provider, service)
+			}
+			services = append(services, service)
+			networkLocations = append(networkLocations, doc.location(fmt.Sprintf("services.%d.network", i)))
+			recordLocations = append(recordLocations, doc.location(fmt.Sprintf("services.%d.value_from", i)))
+			backendLocations = append(backendLocations, doc.location(fmt.Sprintf("services.%d.backends", i)))
+
+			tfvarsContent, err := generateTfvars(serviceConfig.Provider, service.Type, serviceConfig, service, derived)
+			if err != nil {
+				return nil, fmt.Errorf("error generating tfvars for service type %s: %w", service.Type, err)
+			}
+
+			sensitive := doc.hasSecret(fmt.Sprintf("services.%d", i)) || doc.hasConfigSecret()
+			var userData *UserData
+			var dependsOn []string
+			if service.Type == "compute.instance" && (service.UserData != "" || service.UserDataFile != "") {
+				context := UserDataContext{
+					Project:     config.ProjectName,
+					Environment: opts.Env,
+					Provider:    serviceConfig.Provider,
+					Region:      serviceConfig.Region,
+					Zone:        serviceConfig.Zone,
+					ID:          id,
+					Metadata:    service.Metadata,
+				}
+				var secretVars bool
+				userData, secretVars, err = newUserData(doc, i, service, context, plan.Resources)
+				if err != nil {
+					return nil, fmt.Errorf("validation failed: %w", err)
+				}
+				sensitive = sensitive || secretVars
+				// Resources the user data takes outputs from are provisioned first
+				dependsOn = append(dependsOn, userData.resources...)
+			}
+
+			var functionDir string
+			if service.Type == "compute.function" {
+				if functionDir, err = functionSource(doc, service); err != nil {
+					return nil, fmt.Errorf("validation failed: %s: %w", doc.location(fmt.Sprintf("services.%d.source_dir", i)), err)
+				}
+			}
+
+			plan.Resources = append(plan.Resources, ResourcePlan{
+				ID:             id,
+				Type:           service.Type,
+				TfVars:         tfvarsContent,
+				ModuleDir:      GetServiceFolderName(service.Type),
+				Provider:       serviceConfig.Provider,
+				Region:         serviceConfig.Region,
+				Zone:           serviceConfig.Zone,
+				GenerateSSHKey: service.Type == "compute.instance" && service.SSHPublicKey == "",
+				UserData:       userData,
+				DependsOn:      dependsOn,
+				FunctionSource: functionDir,
+				Sensitive:      sensitive,
+			})
 		}
+	}
 
-		// Determine ID
-		var id string
-		if service.InstanceID != "" {
-			id = service.InstanceID
-		} else if service.BucketID != "" {
-			id = service.BucketID
-		} else {
-			// Fallback ID
-			id = fmt.Sprintf("%s-%d", GetServiceFolderName(service.Type), len(plan.Resources)+1)
-		}
-
-		plan.Resources = append(plan.Resources, ResourcePlan{
-			ID:        id,
-			Type:      service.Type,
-			TfVars:    tfvarsContent,
-			ModuleDir: GetServiceFolderName(service.Type),
-		})
+	if len(regionProblems) > 0 {
+		return nil, fmt.Errorf("validation failed: %s", strings.Join(regionProblems, "; "))
+	}
+
+	// Every resource needs its own directory and cloud resource names
+	if err := checkResourceIDs(plan.Resources, idLocations); err != nil {
+		return nil, err
+	}
+
+	// Instances joining a network are provisioned after it, load balancers
+	// after their backends and records after the resources they take their
+	// value from
+	if err := linkNetworks(plan, services, subnets, networkLocations); err != nil {
+		return nil, err
+	}
+	if err := linkLoadBalancers(plan, services, doc.copies, subnets, backendLocations); err != nil {
+		return nil, err
+	}
+	if err := linkRecords(plan, services, recordLocations, rootPath); err != nil {
+		return nil, err
+	}
+	plan.Resources, err = orderResources(plan.Resources)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	// A project spanning several providers keeps all resources in one directory
+	providers := plan.Providers()
+	if len(providers) == 1 {
+		plan.Provider = providers[0]
+	}
+
+	base := filepath.Join(rootPath, "provisioning")
+	if opts.Env != "" {
+		base = filepath.Join(base, opts.Env)
+	}
+	if plan.OutputDir, err = outputDir(base, sanitizedProjectName, providers); err != nil {
+		return nil, err
 	}
 
 	return plan, nil
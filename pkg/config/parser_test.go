@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		want    []Service
+	}{
+		{"no regions", Service{InstanceID: "web", Region: "eu-west-1"}, []Service{{InstanceID: "web", Region: "eu-west-1"}}},
+		{"one copy per region", Service{InstanceID: "web", Regions: []string{"eu-west-1", "us-east-1"}}, []Service{
+			{InstanceID: "web-eu-west-1", Region: "eu-west-1"},
+			{InstanceID: "web-us-east-1", Region: "us-east-1"},
+		}},
+		{"region names are slugged", Service{BucketID: "assets", Regions: []string{"West Europe", "Sweden Central"}}, []Service{
+			{BucketID: "assets-west-europe", Region: "West Europe"},
+			{BucketID: "assets-sweden-central", Region: "Sweden Central"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := expandRegions(tt.service); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expandRegions = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []string
+		providers []string
+		want      string
+		err       string
+	}{
+		{"single provider", nil, []string{"aws"}, "aws/demo", ""},
+		{"several providers", nil, []string{"aws", "gcp"}, "multi/demo", ""},
+		{"provider added", []string{"aws/demo"}, []string{"aws", "gcp"}, "aws/demo", ""},
+		{"provider removed", []string{"multi/demo"}, []string{"gcp"}, "multi/demo", ""},
+		{"other projects ignored", []string{"gcp/other", "azure/demo"}, []string{"gcp"}, "gcp/demo", ""},
+		{"several directories", []string{"aws/demo", "multi/demo"}, []string{"aws", "gcp"}, "", `project "demo" is provisioned in several directories`},
+	}
+	for _, tt := range tests {
+		base := t.TempDir()
+		for _, dir := range tt.existing {
+			if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := outputDir(base, "demo", tt.providers)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if want := filepath.Join(base, tt.want); err != nil || got != want {
+			t.Errorf("%s: outputDir = %q, %v, want %q", tt.name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RegionInfo describes a region of the offline region catalog.
+type RegionInfo struct {
+	Name  string   `json:"name"`
+	Zones []string `json:"zones"`
+}
+
+// RegionCatalog lists the regions and zones of every provider
+// (parser/regions.json).
+type RegionCatalog struct {
+	Version   string                           `json:"version"`
+	Providers map[string]map[string]RegionInfo `json:"providers"`
+}
+
+// LoadRegionCatalog reads parser/regions.json below rootPath.
+func LoadRegionCatalog(rootPath string) (*RegionCatalog, error) {
+	path := filepath.Join(rootPath, "parser", "regions.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading region catalog from %s: %w", path, err)
+	}
+
+	var catalog RegionCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("error parsing region catalog: %w", err)
+	}
+	return &catalog, nil
+}
+
+// Regions returns the region IDs of provider in sorted order.
+func (c *RegionCatalog) Regions(provider string) []string {
+	regions := make([]string, 0, len(c.Providers[provider]))
+	for id := range c.Providers[provider] {
+		regions = append(regions, id)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// normalizeRegion maps spellings like "Sweden Central" to catalog IDs.
+func normalizeRegion(provider, region string) string {
+	region = strings.ToLower(strings.TrimSpace(region))
+	if provider == "azure" {
+		region = strings.ReplaceAll(region, " ", "")
+	}
+	return region
+}
+
+// Resolve validates region and zone for provider and returns them normalized.
+// A zone given as region is split into both; GCP gets the first zone of the
+// region when none is given since its compute module requires one.
+func (c *RegionCatalog) Resolve(provider, region, zone string) (string, string, error) {
+	regions, ok := c.Providers[provider]
+	if !ok {
+		return "", "", fmt.Errorf("no regions known for provider %q", provider)
+	}
+
+	id := normalizeRegion(provider, region)
+	if _, found := regions[id]; !found {
+		parents := zoneRegions(regions, id)
+		switch {
+		case len(parents) == 0:
+			msg := fmt.Sprintf("unknown %s region %q", provider, region)
+			if suggestion := closestRegion(regions, id); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			return "", "", fmt.Errorf("%s; run 'provisioner regions %s' for the list", msg, provider)
+		case len(parents) > 1:
+			// Azure zones are numbered the same in every region
+			return "", "", fmt.Errorf("%q is a zone of %d %s regions, not a region (set the region and \"zone\": %q)", region, len(parents), provider, id)
+		}
+		parent := parents[0]
+		if zone != "" && zone != id {
+			return "", "", fmt.Errorf("region %q is a zone of %q and conflicts with zone %q", region, parent, zone)
+		}
+		id, zone = parent, id
+	}
+
+	info := regions[id]
+	if zone != "" {
+		zone = strings.ToLower(strings.TrimSpace(zone))
+		if !containsString(info.Zones, zone) {
+			if len(info.Zones) == 0 {
+				return "", "", fmt.Errorf("region %q has no availability zones", id)
+			}
+			return "", "", fmt.Errorf("zone %q is not in region %q (zones: %s)", zone, id, strings.Join(info.Zones, ", "))
+		}
+	} else if provider == "gcp" && len(info.Zones) > 0 {
+		zone = info.Zones[0]
+	}
+	return id, zone, nil
+}
+
+// zoneRegions returns the regions having a zone named zone, sorted.
+func zoneRegions(regions map[string]RegionInfo, zone string) []string {
+	var ids []string
+	for id, info := range regions {
+		if containsString(info.Zones, zone) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// closestRegion suggests a region for a misspelled one, or "".
+func closestRegion(regions map[string]RegionInfo, region string) string {
+	best, bestDistance := "", 4
+	for id, info := range regions {
+		for _, candidate := range []string{id, strings.ToLower(info.Name)} {
+			if d := editDistance(region, candidate); d < bestDistance || (d == bestDistance && id < best) {
+				best, bestDistance = id, d
+			}
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegionCatalogResolve(t *testing.T) {
+	catalog := &RegionCatalog{Providers: map[string]map[string]RegionInfo{
+		"aws": {
+			"eu-north-1": {Name: "Europe (Stockholm)", Zones: []string{"eu-north-1a", "eu-north-1b"}},
+			"eu-west-1":  {Name: "Europe (Ireland)", Zones: []string{"eu-west-1a"}},
+		},
+		"azure": {
+			"swedencentral": {Name: "Sweden Central", Zones: []string{"1", "2", "3"}},
+			"westeurope":    {Name: "West Europe", Zones: []string{"1", "2", "3"}},
+			"norwaywest":    {Name: "Norway West"},
+		},
+		"gcp": {
+			"europe-north1": {Name: "Finland", Zones: []string{"europe-north1-a", "europe-north1-b"}},
+		},
+	}}
+
+	tests := []struct {
+		provider, region, zone string
+		wantRegion, wantZone   string
+		err                    string
+	}{
+		{provider: "aws", region: "eu-north-1", wantRegion: "eu-north-1"},
+		{provider: "aws", region: " EU-North-1 ", zone: "eu-north-1b", wantRegion: "eu-north-1", wantZone: "eu-north-1b"},
+		{provider: "aws", region: "eu-north-1a", wantRegion: "eu-north-1", wantZone: "eu-north-1a"},
+		{provider: "aws", region: "eu-north-1a", zone: "eu-north-1a", wantRegion: "eu-north-1", wantZone: "eu-north-1a"},
+		{provider: "aws", region: "eu-north-1a", zone: "eu-north-1b", err: `region "eu-north-1a" is a zone of "eu-north-1" and conflicts with zone "eu-north-1b"`},
+		{provider: "aws", region: "eu-west-1", zone: "eu-north-1a", err: `zone "eu-north-1a" is not in region "eu-west-1" (zones: eu-west-1a)`},
+		{provider: "aws", region: "eu-nort-1", err: `unknown aws region "eu-nort-1" (did you mean "eu-north-1"?)`},
+		{provider: "aws", region: "mars-1", err: `unknown aws region "mars-1"; run 'provisioner regions aws' for the list`},
+		{provider: "azure", region: "Sweden Central", zone: "2", wantRegion: "swedencentral", wantZone: "2"},
+		{provider: "azure", region: "westeurope", wantRegion: "westeurope"},
+		{provider: "azure", region: "1", err: `"1" is a zone of 2 azure regions, not a region`},
+		{provider: "azure", region: "norwaywest", zone: "1", err: `region "norwaywest" has no availability zones`},
+		{provider: "gcp", region: "europe-north1", wantRegion: "europe-north1", wantZone: "europe-north1-a"},
+		{provider: "gcp", region: "europe-north1-b", wantRegion: "europe-north1", wantZone: "europe-north1-b"},
+		{provider: "oracle", region: "x", err: `no regions known for provider "oracle"`},
+	}
+	for _, tt := range tests {
+		region, zone, err := catalog.Resolve(tt.provider, tt.region, tt.zone)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Resolve(%s, %q, %q): error %v, want %q", tt.provider, tt.region, tt.zone, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%s, %q, %q): %v", tt.provider, tt.region, tt.zone, err)
+			continue
+		}
+		if region != tt.wantRegion || zone != tt.wantZone {
+			t.Errorf("Resolve(%s, %q, %q) = %q, %q, want %q, %q", tt.provider, tt.region, tt.zone, region, zone, tt.wantRegion, tt.wantZone)
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+)
+
+// rootField is the field name gojsonschema reports for the document root.
+const rootField = "(root)"
+
+// SupportedExtensions lists the config file formats GeneratePlan accepts.
+var SupportedExtensions = []string{".json", ".yaml", ".yml", ".hcl"}
+
+// IsConfigFile reports whether path has a supported config file extension.
+func IsConfigFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, supported := range SupportedExtensions {
+		if ext == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Position is a 1-based line and column in a config source file.
+type Position struct {
+	Line   int
+	Column int
+	// File is set for values merged in from another file (e.g. an overlay).
+	File string
+}
+
+// sourceError is a decoding error at a known position in the source file.
+type sourceError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *sourceError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+func errorAt(pos Position, format string, args ...interface{}) error {
+	return &sourceError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// document is a config file decoded into JSON-compatible values, together with
+// the source position of every value keyed by the dotted field path
+// gojsonschema uses in its errors (e.g. "services.0.size").
+type document struct {
+	Path      string
+	Data      map[string]interface{}
+	positions map[string]Position
+	// secrets holds the fields whose value derives from a secret variable
+	secrets map[string]bool
+	// vars holds the declared variables
+	vars map[string]variable
+	// copies maps the configured IDs of services using count or for_each
+	// to the IDs of their copies
+	copies map[string][]string
+}
+
+func loadDocument(path string) (*document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	doc := &document{Path: path, positions: map[string]Position{}}
+
+	var value interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		value, err = decodeJSON(data, doc.positions)
+	case ".yaml", ".yml":
+		value, err = decodeYAML(data, doc.positions)
+	case ".hcl":
+		value, err = decodeHCL(data, path, doc.positions)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q (expected one of: %s)", filepath.Ext(path), strings.Join(SupportedExtensions, ", "))
+	}
+	if err != nil {
+		var srcErr *sourceError
+		if errors.As(err, &srcErr) {
+			return nil, fmt.Errorf("%s:%d:%d: %s", path, srcErr.Pos.Line, srcErr.Pos.Column, srcErr.Msg)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Round-trip through JSON so every format yields the same value types
+	value, err = normalizeValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: configuration must be an object", path)
+	}
+	doc.Data = obj
+	return doc, nil
+}
+
+func normalizeValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// JSON returns the document as JSON for schema validation and unmarshaling.
+func (d *document) JSON() ([]byte, error) {
+	return json.Marshal(d.Data)
+}
+
+// position finds the source position of field, falling back to the closest
+// enclosing value (e.g. the service object for a missing required property).
+func (d *document) position(field string) (Position, bool) {
+	for {
+		if pos, ok := d.positions[field]; ok {
+			return pos, true
+		}
+		idx := strings.LastIndex(field, ".")
+		if idx < 0 {
+			break
+		}
+		field = field[:idx]
+	}
+	pos, ok := d.positions[rootField]
+	return pos, ok
+}
+
+// location formats field as "file:line:column" for error messages.
+func (d *document) location(field string) string {
+	if pos, ok := d.position(field); ok {
+		file := d.Path
+		if pos.File != "" {
+			file = pos.File
+		}
+		return fmt.Sprintf("%s:%d:%d", file, pos.Line, pos.Column)
+	}
+	return d.Path
+}
+
+func joinField(parent, child string) string {
+	if parent == rootField {
+		return child
+	}
+	return parent + "." + child
+}
+
+// offsetPosition converts a byte offset into a line and column.
+func offsetPosition(data []byte, offset int) Position {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return Position{Line: line, Column: column}
+}
+
+// jsonDecoder walks JSON tokens and records where each value starts.
+type jsonDecoder struct {
+	data      []byte
+	dec       *json.Decoder
+	positions map[string]Position
+}
+
+func decodeJSON(data []byte, positions map[string]Position) (interface{}, error) {
+	d := &jsonDecoder{data: data, dec: json.NewDecoder(bytes.NewReader(data)), positions: positions}
+	value, err := d.value(rootField)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// Offset counts the offending byte as already read
+			return nil, errorAt(offsetPosition(data, max(int(syntaxErr.Offset)-1, 0)), "%v", syntaxErr)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("unexpected end of JSON input")
+		}
+		return nil, err
+	}
+	if _, err := d.dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errorAt(offsetPosition(data, int(d.dec.InputOffset())), "unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+// valueStart skips whitespace and separators following the last token.
+func (d *jsonDecoder) valueStart() int {
+	offset := int(d.dec.InputOffset())
+	for offset < len(d.data) && strings.IndexByte(" \t\r\n:,", d.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (d *jsonDecoder) value(field string) (interface{}, error) {
+	d.positions[field] = offsetPosition(d.data, d.valueStart())
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := map[string]interface{}{}
+		for d.dec.More() {
+			keyTok, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			child, err := d.value(joinField(field, key))
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = child
+		}
+		_, err := d.dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for d.dec.More() {
+			child, err := d.value(joinField(field, strconv.Itoa(len(list))))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, child)
+		}
+		_, err := d.dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+func decodeYAML(data []byte, positions map[string]Position) (interface{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("empty YAML document")
+	}
+	return yamlValue(root.Content[0], rootField, positions)
+}
+
+func yamlValue(node *yaml.Node, field string, positions map[string]Position) (interface{}, error) {
+	pos := Position{Line: node.Line, Column: node.Column}
+	positions[field] = pos
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		value, err := yamlValue(node.Alias, field, positions)
+		// Report the alias itself rather than its anchor
+		positions[field] = pos
+		return value, err
+	case yaml.MappingNode:
+		obj := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			// Merge keys ("<<: *base") inline the aliased mapping
+			if keyNode.Tag == "!!merge" {
+				merged, err := yamlValue(valueNode, field, positions)
+				if err != nil {
+					return nil, err
+				}
+				positions[field] = pos
+				if m, ok := merged.(map[string]interface{}); ok {
+					for k, v := range m {
+						if _, exists := obj[k]; !exists {
+							obj[k] = v
+						}
+					}
+				}
+				continue
+			}
+			child, err := yamlValue(valueNode, joinField(field, keyNode.Value), positions)
+			if err != nil {
+				return nil, err
+			}
+			obj[keyNode.Value] = child
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := []interface{}{}
+		for i, item := range node.Content {
+			child, err := yamlValue(item, joinField(field, strconv.Itoa(i)), positions)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, child)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, errorAt(Position{Line: node.Line, Column: node.Column}, "%v", err)
+		}
+		return value, nil
+	}
+	return nil, errorAt(Position{Line: node.Line, Column: node.Column}, "unsupported YAML node")
+}
+
+// decodeHCL reads the native HCL config format. Top-level attributes map to
+// config fields and each `service "<type>" { ... }` block becomes an entry of
+// services. Other blocks become objects, or lists of objects when repeated.
+func decodeHCL(data []byte, filename string, positions map[string]Position) (interface{}, error) {
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, hclError(diags)
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected HCL body type %T", file.Body)
+	}
+	positions[rootField] = Position{Line: 1, Column: 1}
+	return hclBody(body, data, rootField, positions)
+}
+
+func hclPosition(pos hcl.Pos) Position {
+	return Position{Line: pos.Line, Column: pos.Column}
+}
+
+// hclError reports the first error diagnostic without the file name hcl puts
+// in front of it, since loadDocument adds it for all formats.
+func hclError(diags hcl.Diagnostics) error {
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		msg := diag.Summary
+		if diag.Detail != "" {
+			msg += "; " + diag.Detail
+		}
+		if diag.Subject != nil {
+			return errorAt(hclPosition(diag.Subject.Start), "%s", msg)
+		}
+		return errors.New(msg)
+	}
+	return diags
+}
+
+func hclBody(body *hclsyntax.Body, src []byte, field string, positions map[string]Position) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+
+	names := make([]string, 0, len(body.Attributes))
+	for name := range body.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attr := body.Attributes[name]
+		child := joinField(field, name)
+		start := attr.Expr.Range().Start
+		positions[child] = hclPosition(start)
+
+		value, err := hclExprValue(attr.Expr, src)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = value
+	}
+
+	// Group blocks by type first so repeated blocks become lists
+	var blockTypes []string
+	blocks := map[string][]*hclsyntax.Block{}
+	for _, block := range body.Blocks {
+		if _, seen := blocks[block.Type]; !seen {
+			blockTypes = append(blockTypes, block.Type)
+		}
+		blocks[block.Type] = append(blocks[block.Type], block)
+	}
+
+	for _, blockType := range blockTypes {
+		key := blockType
+		if blockType == "service" {
+			key = "services"
+		}
+		if _, exists := obj[key]; exists {
+			r := blocks[blockType][0].TypeRange
+			return nil, errorAt(hclPosition(r.Start), "%q is defined both as attribute and block", key)
+		}
+
+		var items []interface{}
+		for i, block := range blocks[blockType] {
+			itemField := joinField(field, key)
+			if key == "services" || len(blocks[blockType]) > 1 {
+				itemField = joinField(itemField, strconv.Itoa(i))
+			}
+			positions[itemField] = hclPosition(block.TypeRange.Start)
+
+			item, err := hclBody(block.Body, src, itemField, positions)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case key == "services" && len(block.Labels) == 1:
+				if _, exists := item["type"]; exists {
+					r := block.LabelRanges[0]
+					return nil, errorAt(hclPosition(r.Start), "service type given both as label and attribute")
+				}
+				item["type"] = block.Labels[0]
+				positions[joinField(itemField, "type")] = hclPosition(block.LabelRanges[0].Start)
+			case len(block.Labels) > 0:
+				r := block.LabelRanges[0]
+				return nil, errorAt(hclPosition(r.Start), "unexpected labels on %q block", blockType)
+			}
+			items = append(items, item)
+		}
+
+		if key == "services" || len(items) > 1 {
+			obj[key] = items
+		} else {
+			obj[key] = items[0]
+		}
+	}
+
+	return obj, nil
+}
+
+// hclExprValue converts a constant expression to its JSON value. References
+// and function calls (var.x, env.X, file("...")) cannot be evaluated here and
+// are kept as "${...}" strings for interpolate, so HCL configs may use them
+// both bare and inside templates.
+func hclExprValue(expr hclsyntax.Expression, src []byte) (interface{}, error) {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr, *hclsyntax.FunctionCallExpr:
+		return "${" + hclSource(e, src) + "}", nil
+	case *hclsyntax.TemplateWrapExpr:
+		return "${" + hclSource(e.Wrapped, src) + "}", nil
+	case *hclsyntax.TemplateExpr:
+		if e.IsStringLiteral() {
+			break
+		}
+		var b strings.Builder
+		for _, part := range e.Parts {
+			if lit, ok := part.(*hclsyntax.LiteralValueExpr); ok && lit.Val.Type() == cty.String {
+				// Literal "${" came from a "$${" escape; keep it escaped
+				b.WriteString(strings.ReplaceAll(lit.Val.AsString(), "${", "$${"))
+				continue
+			}
+			b.WriteString("${" + hclSource(part, src) + "}")
+		}
+		return b.String(), nil
+	case *hclsyntax.ObjectConsExpr:
+		obj := map[string]interface{}{}
+		for _, item := range e.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() {
+				return nil, hclError(diags)
+			}
+			if key.Type() != cty.String {
+				return nil, errorAt(hclPosition(item.KeyExpr.Range().Start), "object keys must be strings")
+			}
+			value, err := hclExprValue(item.ValueExpr, src)
+			if err != nil {
+				return nil, err
+			}
+			obj[key.AsString()] = value
+		}
+		return obj, nil
+	case *hclsyntax.TupleConsExpr:
+		list := make([]interface{}, 0, len(e.Exprs))
+		for _, item := range e.Exprs {
+			value, err := hclExprValue(item, src)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, hclError(diags)
+	}
+
+	data, err := ctyjson.SimpleJSONValue{Value: val}.MarshalJSON()
+	if err != nil {
+		return nil, errorAt(hclPosition(expr.Range().Start), "%v", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func hclSource(expr hclsyntax.Expression, src []byte) string {
+	r := expr.Range()
+	return string(r.SliceBytes(src))
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDocumentFormats(t *testing.T) {
+	sources := map[string]string{
+		"config.json": `{
+  "project_name": "demo",
+  "provider": "aws",
+  "region": "eu-north-1",
+  "services": [
+    {
+      "type": "compute.instance",
+      "instance_id": "vm",
+      "size": "small",
+      "os": "ubuntu",
+      "disk_size_gb": 20,
+      "metadata": {"app": "demo"}
+    }
+  ]
+}`,
+		"config.yaml": `project_name: demo
+provider: aws
+region: eu-north-1
+services:
+  - type: compute.instance
+    instance_id: vm
+    size: small
+    os: ubuntu
+    disk_size_gb: 20
+    metadata:
+      app: demo
+`,
+		"config.hcl": `project_name = "demo"
+provider     = "aws"
+region       = "eu-north-1"
+
+service "compute.instance" {
+  instance_id  = "vm"
+  size         = "small"
+  os           = "ubuntu"
+  disk_size_gb = 20
+  metadata     = { app = "demo" }
+}
+`,
+	}
+
+	// Expected position of services.0.size in each source
+	sizePositions := map[string]Position{
+		"config.json": {Line: 9, Column: 15},
+		"config.yaml": {Line: 7, Column: 11},
+		"config.hcl":  {Line: 7, Column: 18},
+	}
+
+	dir := t.TempDir()
+	var reference map[string]interface{}
+	for name, content := range sources {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err := loadDocument(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if reference == nil {
+			reference = doc.Data
+		} else if !reflect.DeepEqual(reference, doc.Data) {
+			t.Errorf("%s: decoded to %v, want %v", name, doc.Data, reference)
+		}
+
+		if pos, _ := doc.position("services.0.size"); pos != sizePositions[name] {
+			t.Errorf("%s: services.0.size at %+v, want %+v", name, pos, sizePositions[name])
+		}
+		// Missing fields resolve to the enclosing service
+		if pos, _ := doc.position("services.0.missing"); pos != doc.positions["services.0"] {
+			t.Errorf("%s: fallback position %+v, want %+v", name, pos, doc.positions["services.0"])
+		}
+	}
+}
+
+func TestLoadDocumentSyntaxErrorPosition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(path, []byte("{\n  \"a\": 1,\n  \"b\" 2\n}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadDocument(path)
+	if err == nil {
+		t.Fatal("expected syntax error")
+	}
+	want := path + ":3:7: invalid character '2' after object key"
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// userDataLimits are the provider limits on the size of the rendered user
+// data in bytes.
+var userDataLimits = map[string]int{
+	"aws":   16 << 10,
+	"azure": 64 << 10,
+	"gcp":   256 << 10,
+}
+
+// UserDataContext holds the values user data templates refer to as {{ .Field }}.
+type UserDataContext struct {
+	Project     string
+	Environment string
+	Provider    string
+	Region      string
+	Zone        string
+	ID          string
+	Metadata    map[string]string
+}
+
+// UserData is the user data template of a compute instance. It is checked
+// when the plan is generated and rendered at provision time, once the outputs
+// of the resources provisioned before the instance are known.
+type UserData struct {
+	// Name is the config location or file of the template
+	Name     string
+	provider string
+	tmpl     *template.Template
+	context  UserDataContext
+	// resources are the IDs of the resources whose outputs the template uses
+	resources []string
+}
+
+// newUserData parses the user data of service, which is the i-th service of
+// doc, and checks it by rendering it with placeholders for the outputs.
+// earlier are the resources that come before the instance in the config; the
+// ones the template takes outputs from are recorded so the instance is
+// provisioned after them. sensitive reports whether the template uses secret
+// variables.
+func newUserData(doc *document, i int, service Service, context UserDataContext, earlier []ResourcePlan) (userData *UserData, sensitive bool, err error) {
+	name := doc.location(fmt.Sprintf("services.%d.user_data", i))
+	text := service.UserData
+	if service.UserDataFile != "" {
+		path, err := resolvePath(filepath.Dir(doc.Path), service.UserDataFile)
+		if err != nil {
+			return nil, false, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: error reading user data: %w", doc.location(fmt.Sprintf("services.%d.user_data_file", i)), err)
+		}
+		name, text = path, string(data)
+	}
+
+	var resources []string
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"var": func(varName string) (interface{}, error) {
+			v, ok := doc.vars[varName]
+			if !ok {
+				return nil, fmt.Errorf("undefined variable %q", varName)
+			}
+			if v.Secret {
+				sensitive = true
+			}
+			return v.Value, nil
+		},
+		"output": func(id, output string) (interface{}, error) {
+			for _, res := range earlier {
+				if res.ID == id {
+					if !containsString(resources, id) {
+						resources = append(resources, id)
+					}
+					return fmt.Sprintf("<%s.%s>", id, output), nil
+				}
+			}
+			return nil, fmt.Errorf("resource %q is not provisioned before this instance", id)
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing user data: %w", err)
+	}
+
+	userData = &UserData{Name: name, provider: context.Provider, tmpl: tmpl, context: context}
+	rendered, err := userData.execute(tmpl)
+	if err != nil {
+		return nil, false, fmt.Errorf("error rendering user data: %w", err)
+	}
+	userData.resources = resources
+	if err := checkUserData(context.Provider, rendered); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", name, err)
+	}
+	return userData, sensitive, nil
+}
+
+// Render renders the user data. outputs holds the outputs of the resources
+// provisioned so far by resource ID.
+func (u *UserData) Render(outputs map[string]map[string]interface{}) (string, error) {
+	tmpl, err := u.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(template.FuncMap{
+		"output": func(id, output string) (interface{}, error) {
+			values, ok := outputs[id]
+			if !ok {
+				return nil, fmt.Errorf("no outputs of resource %q", id)
+			}
+			value, ok := values[output]
+			if !ok {
+				return nil, fmt.Errorf("resource %q has no output %q", id, output)
+			}
+			return value, nil
+		},
+	})
+
+	rendered, err := u.execute(tmpl)
+	if err != nil {
+		return "", err
+	}
+	if err := checkUserData(u.provider, rendered); err != nil {
+		return "", fmt.Errorf("%s: %w", u.Name, err)
+	}
+	return rendered, nil
+}
+
+func (u *UserData) execute(tmpl *template.Template) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, u.context); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// checkUserData enforces the provider size limit and checks the YAML syntax
+// of cloud-config documents. Scripts and other cloud-init formats are passed
+// on as they are.
+func checkUserData(provider, userData string) error {
+	if limit, ok := userDataLimits[provider]; ok && len(userData) > limit {
+		return fmt.Errorf("user data is %d bytes, %s allows at most %d", len(userData), provider, limit)
+	}
+
+	if strings.HasPrefix(userData, "#cloud-config") {
+		var doc interface{}
+		if err := yaml.Unmarshal([]byte(userData), &doc); err != nil {
+			return fmt.Errorf("invalid cloud-config YAML: %v", err)
+		}
+		if _, ok := doc.(map[string]interface{}); doc != nil && !ok {
+			return fmt.Errorf("invalid cloud-config: the document must be a mapping")
+		}
+	}
+	return nil
+}
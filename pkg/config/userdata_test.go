@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckUserData(t *testing.T) {
+	script := func(size int) string {
+		return "#!/bin/sh\n" + strings.Repeat("#", size-len("#!/bin/sh\n"))
+	}
+	tests := []struct {
+		name     string
+		provider string
+		userData string
+		err      string
+	}{
+		{"AWS limit", "aws", script(16 << 10), ""},
+		{"over the AWS limit", "aws", script(16<<10 + 1), "user data is 16385 bytes, aws allows at most 16384"},
+		{"Azure limit", "azure", script(64 << 10), ""},
+		{"over the Azure limit", "azure", script(64<<10 + 1), "user data is 65537 bytes, azure allows at most 65536"},
+		{"GCP limit", "gcp", script(256 << 10), ""},
+		{"over the GCP limit", "gcp", script(256<<10 + 1), "user data is 262145 bytes, gcp allows at most 262144"},
+		{"cloud-config", "aws", "#cloud-config\npackages:\n  - nginx\n", ""},
+		{"empty cloud-config", "aws", "#cloud-config\n", ""},
+		{"invalid cloud-config YAML", "gcp", "#cloud-config\npackages: [nginx\n", "invalid cloud-config YAML"},
+		{"cloud-config list", "azure", "#cloud-config\n- nginx\n", "the document must be a mapping"},
+		{"scripts are not parsed", "aws", "#!/bin/sh\necho ${HOME\n", ""},
+	}
+	for _, tt := range tests {
+		err := checkUserData(tt.provider, tt.userData)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestUserDataOutputsAreProvisionedFirst(t *testing.T) {
+	rootPath, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadGeneratorConfig(rootPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// db joins net, which comes later in the config, and app takes an
+	// output of db
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+  "project_name": "order-demo",
+  "provider": "aws",
+  "region": "eu-north-1",
+  "services": [
+    {"type": "compute.instance", "instance_id": "db", "size": "small", "os": "ubuntu-24.04", "network": "net"},
+    {"type": "compute.instance", "instance_id": "app", "size": "small", "os": "ubuntu-24.04",
+     "user_data": "#!/bin/sh\necho {{ output \"db\" \"private_ip\" }} > /etc/db-host\n"},
+    {"type": "network.vpc", "network_id": "net", "cidr": "10.20.0.0/16"}
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := GeneratePlan(configPath, rootPath, PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, res := range plan.Resources {
+		order = append(order, res.ID)
+	}
+	if want := []string{"net", "db", "app"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+
+	// Rendering in plan order finds the outputs of db
+	outputs := map[string]map[string]interface{}{
+		"net": {"network_id": "vpc-0123"},
+		"db":  {"private_ip": "10.20.0.4"},
+	}
+	rendered, err := plan.Resources[2].UserData.Render(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#!/bin/sh\necho 10.20.0.4 > /etc/db-host\n"; rendered != want {
+		t.Errorf("rendered %q, want %q", rendered, want)
+	}
+}
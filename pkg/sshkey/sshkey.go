@@ -0,0 +1,140 @@
+// Package sshkey manages the SSH key pairs the provisioner generates for
+// compute instances. Keys live next to the resource state in the
+// provisioning directory so they never collide across projects.
+package sshkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RSA keeps the generated keys usable on Azure, which rejects ed25519 for
+// admin_ssh_key.
+const rsaBits = 4096
+
+type KeyPair struct {
+	PrivateKeyPath string
+	// PublicKey is in authorized_keys format without a trailing newline.
+	PublicKey string
+}
+
+func PrivateKeyPath(dir, name string) string {
+	return filepath.Join(dir, name+".pem")
+}
+
+func publicKeyPath(dir, name string) string {
+	return filepath.Join(dir, name+".pub")
+}
+
+// Ensure returns the key pair stored in dir for name, generating it on first
+// use. A non-empty passphrase encrypts newly generated private keys; it is
+// only needed for existing keys if their public half has gone missing.
+func Ensure(dir, name string, passphrase []byte) (*KeyPair, bool, error) {
+	privPath := PrivateKeyPath(dir, name)
+	pubPath := publicKeyPath(dir, name)
+
+	if _, err := os.Stat(privPath); err == nil {
+		pub, err := existingPublicKey(privPath, pubPath, passphrase)
+		if err != nil {
+			return nil, false, err
+		}
+		return &KeyPair{PrivateKeyPath: privPath, PublicKey: pub}, false, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, rsaBits)
+	if err != nil {
+		return nil, false, fmt.Errorf("error generating key: %w", err)
+	}
+
+	var block *pem.Block
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, name, passphrase)
+		if err != nil {
+			return nil, false, fmt.Errorf("error encrypting private key: %w", err)
+		}
+	} else {
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	}
+
+	pub, err := authorizedKey(&key.PublicKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := writeFile(privPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, false, err
+	}
+	if err := writeFile(pubPath, []byte(pub+"\n"), 0644); err != nil {
+		return nil, false, err
+	}
+
+	return &KeyPair{PrivateKeyPath: privPath, PublicKey: pub}, true, nil
+}
+
+// Remove deletes the key pair for name from dir. Missing files are ignored.
+func Remove(dir, name string) error {
+	var errs []error
+	for _, path := range []string{PrivateKeyPath(dir, name), publicKeyPath(dir, name)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func existingPublicKey(privPath, pubPath string, passphrase []byte) (string, error) {
+	if data, err := os.ReadFile(pubPath); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	data, err := os.ReadFile(privPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading private key: %w", err)
+	}
+
+	var raw interface{}
+	if len(passphrase) > 0 {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	} else {
+		raw, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error parsing private key %s: %w", privPath, err)
+	}
+
+	key, ok := raw.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type %T in %s", raw, privPath)
+	}
+
+	pub, err := authorizedKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return pub, writeFile(pubPath, []byte(pub+"\n"), 0644)
+}
+
+func authorizedKey(key *rsa.PublicKey) (string, error) {
+	sshPub, err := ssh.NewPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("error encoding public key: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))), nil
+}
+
+// writeFile writes data and enforces perm even if the file already existed.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,134 @@
+package sshkey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func checkMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != want {
+		t.Errorf("%s: mode %#o, want %#o", filepath.Base(path), mode, want)
+	}
+}
+
+func TestEnsureGeneratesAndReuses(t *testing.T) {
+	dir := t.TempDir()
+	key, created, err := Ensure(dir, "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("first call did not report a new key")
+	}
+	if key.PrivateKeyPath != PrivateKeyPath(dir, "web") {
+		t.Errorf("private key at %s, want %s", key.PrivateKeyPath, PrivateKeyPath(dir, "web"))
+	}
+	checkMode(t, key.PrivateKeyPath, 0600)
+	checkMode(t, publicKeyPath(dir, "web"), 0644)
+
+	data, err := os.ReadFile(key.PrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(ssh.MarshalAuthorizedKey(signer.PublicKey())); got != key.PublicKey+"\n" {
+		t.Errorf("public key %q does not match the private key (%q)", key.PublicKey, got)
+	}
+
+	// An existing key is reused
+	again, created, err := Ensure(dir, "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || again.PublicKey != key.PublicKey {
+		t.Errorf("second call generated a new key (created %v)", created)
+	}
+
+	// A missing public key is derived from the private key
+	if err := os.Remove(publicKeyPath(dir, "web")); err != nil {
+		t.Fatal(err)
+	}
+	restored, created, err := Ensure(dir, "web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || restored.PublicKey != key.PublicKey {
+		t.Errorf("restored public key %q, want %q", restored.PublicKey, key.PublicKey)
+	}
+	checkMode(t, publicKeyPath(dir, "web"), 0644)
+}
+
+func TestEnsureWithPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	passphrase := []byte("correct horse")
+	key, _, err := Ensure(dir, "db", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMode(t, key.PrivateKeyPath, 0600)
+
+	data, err := os.ReadFile(key.PrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing *ssh.PassphraseMissingError
+	if _, err := ssh.ParseRawPrivateKey(data); !errors.As(err, &missing) {
+		t.Fatalf("private key is not encrypted (parse error: %v)", err)
+	}
+	if _, err := ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase); err != nil {
+		t.Fatalf("private key does not open with the passphrase: %v", err)
+	}
+
+	// Recovering the public key needs the passphrase
+	if err := os.Remove(publicKeyPath(dir, "db")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Ensure(dir, "db", nil); err == nil {
+		t.Error("expected an error reading the encrypted key without a passphrase")
+	}
+	if _, _, err := Ensure(dir, "db", []byte("wrong")); err == nil {
+		t.Error("expected an error reading the encrypted key with a wrong passphrase")
+	}
+	restored, created, err := Ensure(dir, "db", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || restored.PublicKey != key.PublicKey {
+		t.Errorf("restored public key %q, want %q", restored.PublicKey, key.PublicKey)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := Ensure(dir, "web", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(dir, "web"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{PrivateKeyPath(dir, "web"), publicKeyPath(dir, "web")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", filepath.Base(path))
+		}
+	}
+	// Removing again is not an error
+	if err := Remove(dir, "web"); err != nil {
+		t.Errorf("removing a missing key: %v", err)
+	}
+}